@@ -0,0 +1,274 @@
+// Package bot plays speed scrabble as a computer player, building its boards with the
+// solver.  A bot connects through the same messages as any other player, either in process
+// through a Pipe or to a server over a real socket.
+package bot
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/kathrelkeld/speed-scrabble/game"
+	"github.com/kathrelkeld/speed-scrabble/msg"
+	"github.com/kathrelkeld/speed-scrabble/solver"
+)
+
+// A Level sets how well a bot plays.
+type Level struct {
+	Name string
+	// PerTile is how long the bot takes to place each tile, so it never verifies a board
+	// sooner than this times the number of tiles after the round starts.
+	PerTile time.Duration
+	// Budget is how long the solver may search for each board.  Bots which give up sooner
+	// draw more tiles.
+	Budget time.Duration
+}
+
+// The standard levels.
+var (
+	Easy   = Level{Name: "easy", PerTile: 8 * time.Second, Budget: 250 * time.Millisecond}
+	Medium = Level{Name: "medium", PerTile: 4 * time.Second, Budget: time.Second}
+	Expert = Level{Name: "expert", PerTile: 1500 * time.Millisecond, Budget: 5 * time.Second}
+)
+
+// Levels lists the standard levels by name.
+var Levels = map[string]Level{
+	Easy.Name:   Easy,
+	Medium.Name: Medium,
+	Expert.Name: Expert,
+}
+
+// A Lookup returns the dictionary the named game checks words against, given the name of its
+// word list, or nil if the bot does not have that list.
+type Lookup func(gameName, list string) game.Dictionary
+
+// WordLists looks up the given word lists by name alone, for bots which cannot know the house
+// rules of a game, such as those playing over a real socket.
+func WordLists(dicts map[string]game.Dictionary) Lookup {
+	return func(gameName, list string) game.Dictionary {
+		return dicts[list]
+	}
+}
+
+// Dial connects to the server at url, such as "ws://localhost:8888/connect", for a bot to
+// play over a real socket.
+func Dial(url string) (game.WebsocketConn, error) {
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
+// A Bot is a single computer player.
+type Bot struct {
+	Name  string
+	level Level
+	conn  game.WebsocketConn
+	words Lookup // Finds the dictionary of each game the bot plays.
+
+	joined   bool        // Whether the game has accepted the bot.
+	cfg      game.Config // Settings of the game, once known.
+	round    int         // Counts rounds, so that searches from earlier ones are ignored.
+	playing  bool        // Whether a round is running.
+	start    time.Time   // When the round started.
+	hand     []game.Tile // Every tile served this round.
+	solution game.Board  // The last board found which uses every tile in hand, if any.
+	solved   chan solveResult
+	done     chan struct{}    // Closed once the bot stops playing.
+	verify   <-chan time.Time // Fires when the solution may be sent.
+}
+
+// A solveResult is the outcome of a search for a board using the first tiles of a hand.
+type solveResult struct {
+	round int
+	tiles int
+	board game.Board
+	err   error
+}
+
+// New returns a bot which plays at the given level over conn, with the dictionaries found by
+// words.
+func New(conn game.WebsocketConn, name string, level Level, words Lookup) *Bot {
+	return &Bot{
+		Name:   name,
+		level:  level,
+		conn:   conn,
+		words:  words,
+		solved: make(chan solveResult),
+		done:   make(chan struct{}),
+	}
+}
+
+// Run joins a game by sending join, with the bot's name filled in, and plays until the
+// connection closes or the bot is removed from the game.
+func (b *Bot) Run(join msg.JoinGameData) {
+	defer close(b.done)
+	defer b.conn.Close()
+	join.Name = b.Name
+	join.Protocol = msg.ProtocolSparse
+	join.Bot = true
+	b.send(msg.JoinGame, join)
+
+	msgs := make(chan []byte)
+	go func() {
+		defer close(msgs)
+		for {
+			_, m, err := b.conn.ReadMessage()
+			if err != nil || len(m) == 0 {
+				return
+			}
+			select {
+			case msgs <- m:
+			case <-b.done:
+				return
+			}
+		}
+	}()
+	for {
+		select {
+		case m, ok := <-msgs:
+			if !ok || !b.handle(msg.Type(m[0]), m[1:]) {
+				log.Println("Bot", b.Name, "leaving")
+				return
+			}
+		case r := <-b.solved:
+			b.solveDone(r)
+		case <-b.verify:
+			b.verify = nil
+			if b.playing {
+				b.send(msg.Verify, placedTiles(b.solution))
+			}
+		}
+	}
+}
+
+// handle reacts to a message from the game, and returns false once the bot should leave.
+func (b *Bot) handle(t msg.Type, data []byte) bool {
+	switch t {
+	case msg.PlayerJoined:
+		b.joined = true
+	case msg.RoundReady:
+		// Another player has asked for a new round.  Bots never start one themselves.
+		b.send(msg.RoundReady, nil)
+	case msg.GameInfo:
+		var info msg.GameInfoData
+		if err := json.Unmarshal(data, &info); err != nil {
+			log.Println("Bot", b.Name, "could not read game info:", err)
+			break
+		}
+		b.configure(info)
+	case msg.Start:
+		b.round++
+		b.playing = true
+		b.start = time.Now()
+		b.hand = nil
+		b.solution = nil
+		b.verify = nil
+		if err := json.Unmarshal(data, &b.hand); err != nil {
+			log.Println("Bot", b.Name, "could not read tiles:", err)
+			break
+		}
+		b.solve()
+	case msg.AddTile:
+		var tile game.Tile
+		if err := json.Unmarshal(data, &tile); err != nil {
+			log.Println("Bot", b.Name, "could not read tile:", err)
+			break
+		}
+		b.hand = append(b.hand, tile)
+		b.solve()
+	case msg.Score, msg.Invalid:
+		var score struct{ Win bool }
+		json.Unmarshal(data, &score)
+		if b.playing && !score.Win && b.verify == nil {
+			// The server would not accept the board, so try again with another tile.
+			b.send(msg.AddTile, nil)
+		}
+	case msg.SendBoard:
+		b.playing = false
+		b.send(msg.SendBoard, placedTiles(b.solution))
+	case msg.Result:
+		b.playing = false
+		b.round++
+	case msg.Exit:
+		return false
+	case msg.Error:
+		var text string
+		json.Unmarshal(data, &text)
+		log.Println("Bot", b.Name, "got error:", text)
+		// A game which refused the bot will never send it anything else.
+		return b.joined
+	}
+	return true
+}
+
+// configure sets up the bot to play with the word list, language and word rules of the game.
+func (b *Bot) configure(info msg.GameInfoData) {
+	b.cfg = game.DefaultConfig(b.words(info.GameName, info.WordList))
+	b.cfg.WordList = info.WordList
+	b.cfg.Words = game.WordRulePresets[info.Words]
+	if lang, ok := game.LanguagePacks[info.Language]; ok {
+		b.cfg.Language = lang
+	}
+	if b.cfg.Dict == nil {
+		log.Println("Bot", b.Name, "does not have word list", info.WordList)
+	}
+}
+
+// solve starts searching for a board which uses every tile in hand.
+func (b *Bot) solve() {
+	if b.cfg.Dict == nil {
+		return
+	}
+	round, hand, cfg, budget := b.round, append([]game.Tile(nil), b.hand...), b.cfg, b.level.Budget
+	go func() {
+		board, err := solver.Solve(hand, cfg, budget)
+		select {
+		case b.solved <- solveResult{round, len(hand), board, err}:
+		case <-b.done:
+		}
+	}()
+}
+
+// solveDone sends the board found by a search once the bot has had time to place its
+// tiles, or draws another tile if there was none.  Results for earlier hands are ignored.
+func (b *Bot) solveDone(r solveResult) {
+	if r.round != b.round || r.tiles != len(b.hand) || !b.playing {
+		return
+	}
+	if r.err != nil {
+		log.Println("Bot", b.Name, "drawing a tile:", r.err)
+		b.send(msg.AddTile, nil)
+		return
+	}
+	b.solution = r.board
+	wait := time.Until(b.start.Add(b.level.PerTile * time.Duration(len(b.hand))))
+	b.verify = time.After(wait)
+}
+
+// send sends a message of the given type with the given data to the game.
+func (b *Bot) send(t msg.Type, d interface{}) {
+	m, err := msg.NewSocketData(t, d)
+	if err == nil {
+		err = b.conn.WriteMessage(websocket.TextMessage, m)
+	}
+	if err != nil {
+		log.Println("Bot", b.Name, "could not send", t, err)
+	}
+}
+
+// placedTiles returns every tile of the board as it is sent with msg.ProtocolSparse.
+func placedTiles(board game.Board) []msg.PlacedTile {
+	placed := []msg.PlacedTile{}
+	for y, row := range board {
+		for x, t := range row {
+			if t != nil {
+				placed = append(placed, msg.PlacedTile{X: x, Y: y, Value: t.Value, ID: t.ID})
+			}
+		}
+	}
+	return placed
+}
@@ -0,0 +1,184 @@
+package bot
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/kathrelkeld/speed-scrabble/game"
+	"github.com/kathrelkeld/speed-scrabble/msg"
+)
+
+// instant is a level which plays as soon as it finds a board.
+var instant = Level{Name: "instant", Budget: time.Second}
+
+var makeTiles = game.LanguagePacks["english"].MakeTiles
+
+func send(t *testing.T, conn game.WebsocketConn, typ msg.Type, d interface{}) {
+	t.Helper()
+	m, err := msg.NewSocketData(typ, d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.WriteMessage(websocket.TextMessage, m); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// waitForMsg reads messages until one of the given type arrives, and returns its data.
+func waitForMsg(t *testing.T, conn game.WebsocketConn, typ msg.Type) []byte {
+	t.Helper()
+	got := make(chan []byte)
+	go func() {
+		for {
+			_, m, err := conn.ReadMessage()
+			if err != nil {
+				close(got)
+				return
+			}
+			if msg.Type(m[0]) == typ {
+				got <- m[1:]
+				return
+			}
+		}
+	}()
+	select {
+	case m, ok := <-got:
+		if !ok {
+			t.Fatalf("connection closed waiting for %v", typ)
+		}
+		return m
+	case <-time.After(10 * time.Second):
+		t.Fatalf("timed out waiting for %v", typ)
+	}
+	return nil
+}
+
+func TestBotPlaysHand(t *testing.T) {
+	dict, err := game.LoadDictionaryFile("../game/test_data/dict.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, botConn := Pipe()
+	b := New(botConn, "Bot", instant, WordLists(map[string]game.Dictionary{"test": dict}))
+	done := make(chan struct{})
+	go func() {
+		b.Run(msg.JoinGameData{WordList: "test"})
+		close(done)
+	}()
+
+	var join msg.JoinGameData
+	json.Unmarshal(waitForMsg(t, conn, msg.JoinGame), &join)
+	if join.Name != "Bot" || !join.Bot || join.Protocol != msg.ProtocolSparse || join.WordList != "test" {
+		t.Errorf("bot joined with %+v", join)
+	}
+	send(t, conn, msg.RoundReady, nil)
+	waitForMsg(t, conn, msg.RoundReady)
+	send(t, conn, msg.GameInfo, msg.GameInfoData{WordList: "test", Language: "english"})
+
+	// No word can be made from these tiles, so the bot must draw another.
+	tiles := makeTiles("C", "A", "T")
+	send(t, conn, msg.Start, tiles[:2])
+	waitForMsg(t, conn, msg.AddTile)
+	send(t, conn, msg.AddTile, tiles[2])
+
+	var placed []msg.PlacedTile
+	if err := json.Unmarshal(waitForMsg(t, conn, msg.Verify), &placed); err != nil {
+		t.Fatal(err)
+	}
+	board := game.Board{make([]*game.Tile, 3)}
+	for _, p := range placed {
+		if p.Y != 0 || p.X < 0 || p.X > 2 || tiles[p.ID].Value != p.Value {
+			t.Fatalf("bot placed %+v for tiles %v", placed, tiles)
+		}
+		tile := tiles[p.ID]
+		board[p.Y][p.X] = &tile
+	}
+	if s := board.Score(tiles, game.DefaultConfig(dict)); !s.Win {
+		t.Errorf("bot verified a board which does not win:\n%v%v", board, s)
+	}
+
+	send(t, conn, msg.SendBoard, nil)
+	json.Unmarshal(waitForMsg(t, conn, msg.SendBoard), &placed)
+	if len(placed) != len(tiles) {
+		t.Errorf("bot sent %v tiles at the end of the round; want %v", len(placed), len(tiles))
+	}
+
+	send(t, conn, msg.Exit, "removed")
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("bot did not leave after Exit")
+	}
+}
+
+func TestBotInGame(t *testing.T) {
+	dicts, err := game.LoadWordLists()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ga := game.NewGameAssigner(dicts, nil, game.DefaultWordList, nil)
+	ga.StartBot = func(level string, join msg.JoinGameData) (game.WebsocketConn, error) {
+		serverEnd, botEnd := Pipe()
+		go New(botEnd, level+" bot", instant, ga.GameDictionary).Run(join)
+		return serverEnd, nil
+	}
+	go ga.Run()
+	defer ga.Close()
+
+	player, serverEnd := Pipe()
+	ga.StartNewClient(serverEnd)
+	send(t, player, msg.JoinGame, msg.JoinGameData{Name: "ann", Protocol: msg.ProtocolSparse})
+	waitForMsg(t, player, msg.PlayerJoined)
+	send(t, player, msg.AddBot, "test")
+	time.Sleep(100 * time.Millisecond) // Give the bot time to join.
+
+	// The bot finishes first, so the round ends once this player sends their board.
+	send(t, player, msg.RoundReady, nil)
+	var info msg.GameInfoData
+	json.Unmarshal(waitForMsg(t, player, msg.GameInfo), &info)
+	if len(info.PlayerNames) != 2 {
+		t.Fatalf("game has players %v; want ann and the bot", info.PlayerNames)
+	}
+	waitForMsg(t, player, msg.SendBoard)
+	send(t, player, msg.SendBoard, []msg.PlacedTile{})
+	var result msg.ResultData
+	json.Unmarshal(waitForMsg(t, player, msg.Result), &result)
+	if len(result.Players) != 2 || result.Players[0].Name != "test bot" || !result.Players[0].Win {
+		t.Errorf("got results %+v; want the bot to finish first", result.Players)
+	}
+
+	send(t, player, msg.RemoveBot, nil)
+	time.Sleep(100 * time.Millisecond) // Give the bot time to leave.
+	send(t, player, msg.RoundReady, nil)
+	json.Unmarshal(waitForMsg(t, player, msg.GameInfo), &info)
+	if len(info.PlayerNames) != 1 {
+		t.Errorf("game has players %v after removing the bot; want only ann", info.PlayerNames)
+	}
+}
+
+func TestBotLeavesWhenRefused(t *testing.T) {
+	dict, err := game.LoadDictionaryFile("../game/test_data/dict.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dicts := map[string]game.Dictionary{"test": dict}
+	ga := game.NewGameAssigner(dicts, nil, "test", nil)
+	go ga.Run()
+	defer ga.Close()
+
+	serverEnd, botEnd := Pipe()
+	ga.StartNewClient(serverEnd)
+	done := make(chan struct{})
+	go func() {
+		New(botEnd, "Bot", instant, WordLists(dicts)).Run(msg.JoinGameData{Language: "german"})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("bot did not leave a game which refused it")
+	}
+}
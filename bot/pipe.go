@@ -0,0 +1,64 @@
+package bot
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/kathrelkeld/speed-scrabble/game"
+)
+
+// errClosed is returned by either end of a pipe once it has been closed.
+var errClosed = errors.New("connection closed")
+
+// Pipe returns the two ends of an in-process connection, for a bot to play without a real
+// socket.  Messages written to one end are read from the other.  Closing either end closes
+// both.
+func Pipe() (game.WebsocketConn, game.WebsocketConn) {
+	a := make(chan []byte, 16)
+	b := make(chan []byte, 16)
+	closed := &pipeClosed{ch: make(chan struct{})}
+	return &pipeConn{in: a, out: b, closed: closed}, &pipeConn{in: b, out: a, closed: closed}
+}
+
+// pipeClosed is shared by both ends of a pipe.
+type pipeClosed struct {
+	once sync.Once
+	ch   chan struct{}
+}
+
+// A pipeConn is one end of a Pipe.
+type pipeConn struct {
+	in     <-chan []byte
+	out    chan<- []byte
+	closed *pipeClosed
+}
+
+func (p *pipeConn) ReadMessage() (int, []byte, error) {
+	select {
+	case b := <-p.in:
+		return websocket.TextMessage, b, nil
+	case <-p.closed.ch:
+		return 0, nil, errClosed
+	}
+}
+
+func (p *pipeConn) WriteMessage(t int, b []byte) error {
+	select {
+	case <-p.closed.ch:
+		return errClosed
+	default:
+	}
+	select {
+	case p.out <- b:
+		return nil
+	case <-p.closed.ch:
+		return errClosed
+	}
+}
+
+func (p *pipeConn) Close() error {
+	p.closed.once.Do(func() { close(p.closed.ch) })
+	return nil
+}
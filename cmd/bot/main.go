@@ -0,0 +1,71 @@
+// Command bot plays speed scrabble against a running server as a computer player.  It joins
+// the game for the chosen word list and language, as any other player would, and plays
+// whenever another player starts a round.
+//
+//	go run ./cmd/bot -server ws://localhost:8888/connect -level expert
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/kathrelkeld/speed-scrabble/bot"
+	"github.com/kathrelkeld/speed-scrabble/game"
+	"github.com/kathrelkeld/speed-scrabble/msg"
+)
+
+func main() {
+	server := flag.String("server", "ws://localhost:8888/connect", "websocket address of the server")
+	level := flag.String("level", bot.Medium.Name, "how well to play: easy, medium or expert")
+	name := flag.String("name", "", "name to play under; defaults to one from the level")
+	words := flag.String("words", "", "name of the word list to play with; empty for the language's default")
+	wordsFile := flag.String("words-file", "", "word list or compiled dictionary to load under the -words name, if it is not built in")
+	lang := flag.String("lang", "", "language to play in; empty for English")
+	rule := flag.String("rule", "", "scoring rule of the game to join; empty for the server's default")
+	layout := flag.String("layout", "", "layout of premium squares of the game to join; empty for none")
+	mode := flag.String("mode", "", "preset of word rules of the game to join, such as hard; empty for normal")
+	islands := flag.Bool("islands", false, "join a game which counts every island of tiles")
+	islandPenalty := flag.Int("island-penalty", 0, "points each island after the first costs, with -islands")
+	flag.Parse()
+
+	l, ok := bot.Levels[*level]
+	if !ok {
+		fmt.Fprintf(flag.CommandLine.Output(), "unknown level %q\n", *level)
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *name == "" {
+		*name = strings.Title(l.Name) + " bot"
+	}
+
+	dicts, err := game.LoadWordLists()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *wordsFile != "" {
+		if *words == "" {
+			log.Fatal("-words-file needs a -words name")
+		}
+		if dicts[*words], err = game.LoadDictionaryFile(*wordsFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	conn, err := bot.Dial(*server)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Println("Connected to", *server, "as", *name)
+	bot.New(conn, *name, l, bot.WordLists(dicts)).Run(msg.JoinGameData{
+		WordList:      *words,
+		Language:      *lang,
+		Rule:          *rule,
+		Layout:        *layout,
+		Words:         *mode,
+		Islands:       *islands,
+		IslandPenalty: *islandPenalty,
+	})
+}
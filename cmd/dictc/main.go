@@ -0,0 +1,44 @@
+// Command dictc compiles a word list, with one word per line, into the DAWG format which the
+// server can load in place of the list.  Words are upper cased, as when the list is loaded.
+//
+//	go run ./cmd/dictc -o game/sowpods.dawg game/sowpods.txt
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/kathrelkeld/speed-scrabble/game"
+)
+
+func main() {
+	out := flag.String("o", "", "file to write the compiled dictionary to")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: dictc -o OUTPUT WORDLIST")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *out == "" || flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	words, err := game.LoadDictionaryFile(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := game.WriteDAWG(f, words); err != nil {
+		f.Close()
+		log.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		log.Fatal(err)
+	}
+	log.Println("Compiled", flag.Arg(0), "into", *out)
+}
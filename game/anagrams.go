@@ -0,0 +1,177 @@
+package game
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kathrelkeld/speed-scrabble/msg"
+)
+
+// Limits on anagram queries, so that each stays quick.  Every blank multiplies the search by
+// the number of tiles in the language.
+const (
+	maxRackTiles  = 30
+	maxRackBlanks = 2
+)
+
+// blank stands for any tile in racks and patterns.
+const blank = "?"
+
+// FindAnagrams returns every word in d which can be made from the rack of req, in the given
+// language, where nil means English.  Letters in the pattern, if any, are already in place,
+// so they are not taken from the rack, but every word must use at least one tile from it.
+// Words are grouped by their length in tiles, longest first.
+func FindAnagrams(d Dictionary, lang *LanguagePack, req msg.AnagramRequest) (msg.AnagramData, error) {
+	lang = lang.orEnglish()
+	result := msg.AnagramData{Rack: req.Rack, Pattern: req.Pattern}
+	rack, err := parseRack(req.Rack, lang)
+	if err != nil {
+		return result, err
+	}
+	pattern, err := parseRack(req.Pattern, lang)
+	if err != nil {
+		return result, err
+	}
+	if len(rack) > maxRackTiles {
+		return result, fmt.Errorf("rack has %v tiles; at most %v are allowed", len(rack), maxRackTiles)
+	}
+	if req.Sort != "" && req.Sort != "points" && req.Sort != "alpha" {
+		return result, fmt.Errorf("cannot sort by %q", req.Sort)
+	}
+
+	s := &anagramSearch{
+		dict:    d,
+		lang:    lang,
+		multi:   lang.multiLetterTiles(),
+		counts:  make(map[string]int),
+		pattern: pattern,
+		found:   make(map[string]msg.WordScore),
+		lengths: make(map[string]int),
+	}
+	for _, t := range rack {
+		if t == blank {
+			s.blanks++
+			continue
+		}
+		if s.counts[t] == 0 {
+			s.values = append(s.values, t)
+		}
+		s.counts[t]++
+	}
+	if s.blanks > maxRackBlanks {
+		return result, fmt.Errorf("rack has %v blanks; at most %v are allowed", s.blanks, maxRackBlanks)
+	}
+	sort.Strings(s.values)
+	for t := range lang.Tiles {
+		s.letters = append(s.letters, t)
+	}
+	sort.Strings(s.letters)
+	s.walk("", 0, 0, 0)
+
+	groups := make(map[int][]msg.WordScore)
+	for w, ws := range s.found {
+		n := s.lengths[w]
+		groups[n] = append(groups[n], ws)
+	}
+	for n, words := range groups {
+		sort.Slice(words, func(i, j int) bool {
+			if req.Sort == "points" && words[i].Pts != words[j].Pts {
+				return words[i].Pts > words[j].Pts
+			}
+			return words[i].Word < words[j].Word
+		})
+		result.Groups = append(result.Groups, msg.AnagramGroup{Length: n, Words: words})
+	}
+	sort.Slice(result.Groups, func(i, j int) bool { return result.Groups[i].Length > result.Groups[j].Length })
+	return result, nil
+}
+
+// parseRack splits the letters of a rack or pattern into the tiles of the language, with
+// the longest multi-letter tile possible at each point.  Letters separated by spaces or
+// commas are separate tiles, so "C,H" is a C and an H rather than a CH.
+func parseRack(s string, lang *LanguagePack) ([]string, error) {
+	var tiles []string
+	multi := lang.multiLetterTiles()
+	for _, part := range strings.FieldsFunc(strings.ToUpper(s), func(r rune) bool { return r == ' ' || r == ',' }) {
+		tiles = append(tiles, spell(part, multi)...)
+	}
+	for _, t := range tiles {
+		if _, ok := lang.Tiles[t]; !ok && t != blank {
+			return nil, fmt.Errorf("%v is not a %v tile", t, lang.Name)
+		}
+	}
+	return tiles, nil
+}
+
+// An anagramSearch walks the dictionary by prefix, trying each tile left in the rack at
+// each step, so that only prefixes of real words are followed.
+type anagramSearch struct {
+	dict    Dictionary
+	lang    *LanguagePack
+	multi   []string       // The language's multi-letter tiles, longest first.
+	values  []string       // Each value in the rack, without blanks, sorted.
+	counts  map[string]int // The tiles of each value left in the rack.
+	blanks  int            // The blanks left in the rack.
+	letters []string       // Every value a blank may stand for, sorted.
+	pattern []string       // The tile at each square, or a blank; nil for no pattern.
+	found   map[string]msg.WordScore
+	lengths map[string]int // The length of each word found, in tiles.
+}
+
+// walk extends prefix, which is made of n tiles worth pts points, of which used came from
+// the rack, recording each word it reaches.
+func (s *anagramSearch) walk(prefix string, n, pts, used int) {
+	if s.pattern == nil || n == len(s.pattern) {
+		s.record(prefix, n, pts, used)
+	}
+	if s.pattern != nil {
+		if n == len(s.pattern) {
+			return
+		}
+		if t := s.pattern[n]; t != blank {
+			s.follow(prefix+t, n+1, pts+s.lang.Points[t], used)
+			return
+		}
+	}
+	for _, v := range s.values {
+		if s.counts[v] == 0 {
+			continue
+		}
+		s.counts[v]--
+		s.follow(prefix+v, n+1, pts+s.lang.Points[v], used+1)
+		s.counts[v]++
+	}
+	if s.blanks > 0 {
+		s.blanks--
+		for _, v := range s.letters {
+			s.follow(prefix+v, n+1, pts, used+1)
+		}
+		s.blanks++
+	}
+}
+
+// follow walks on from prefix if any word starts with it.
+func (s *anagramSearch) follow(prefix string, n, pts, used int) {
+	if s.dict.HasPrefix(prefix) {
+		s.walk(prefix, n, pts, used)
+	}
+}
+
+// record adds w, made of n tiles, if it is a word which uses the rack and is spelt with
+// those tiles.  A word found more than once keeps its best points.
+func (s *anagramSearch) record(w string, n, pts, used int) {
+	if n < 2 || used == 0 || !s.dict.Contains(w) {
+		return
+	}
+	// Separate tiles which make up a multi-letter tile, such as C and H for CH, do not
+	// spell the word.
+	if len(spell(w, s.multi)) != n {
+		return
+	}
+	if prev, ok := s.found[w]; ok && prev.Pts >= pts {
+		return
+	}
+	s.found[w] = msg.WordScore{Word: w, Pts: pts}
+	s.lengths[w] = n
+}
@@ -0,0 +1,88 @@
+package game
+
+import (
+	"log"
+
+	"github.com/kathrelkeld/speed-scrabble/msg"
+)
+
+// missedWords is the most words a player is shown which they could have played.
+const missedWords = 5
+
+// An analysisResult is a player's analysis of a round, made away from the game goroutine.
+type analysisResult struct {
+	c     *Client
+	round int
+	data  msg.AnalysisData
+}
+
+// startAnalyses works out what each player could have made from their hand in the round
+// just ended.  Each analysis is sent from Run once it is ready, unless another round has
+// started by then.
+func (g *Game) startAnalyses() {
+	analyze := g.ga.Analyze
+	if analyze == nil {
+		return
+	}
+	cfg := g.config.detached()
+	for c := range g.clients {
+		if c.bot {
+			continue
+		}
+		hand := append([]Tile(nil), g.tiles[:c.servedCnt]...)
+		played := make(map[string]bool)
+		if s := g.lastScores[c]; s != nil {
+			for _, w := range s.Words {
+				played[w.Value] = true
+			}
+		}
+		go func(c *Client, cfg Config, round int) {
+			board, words := analyze(hand, cfg)
+			r := analysisResult{c, round, analysis(board, words, played, cfg.Language.multiLetterTiles())}
+			select {
+			case g.analyses <- r:
+			case <-g.quit:
+			}
+		}(c, cfg, g.round)
+	}
+}
+
+// analysis returns the analysis of a hand, given a board using every tile of it, if any,
+// and the words which can be made from it, best first.  The longest word is the one of the
+// most tiles, spelt with the given multi-letter tiles.
+func analysis(board Board, words []msg.WordScore, played map[string]bool, multi []string) msg.AnalysisData {
+	a := msg.AnalysisData{Board: board.placedTiles()}
+	longest := 0
+	for _, w := range words {
+		if n := len(spell(w.Word, multi)); n > longest {
+			a.Longest, longest = w.Word, n
+		}
+		if !played[w.Word] && len(a.Missed) < missedWords {
+			a.Missed = append(a.Missed, w)
+		}
+	}
+	return a
+}
+
+// sendAnalysis sends an analysis to its player, if they are still in the game and no other
+// round has started.
+func (g *Game) sendAnalysis(r analysisResult) {
+	if _, ok := g.clients[r.c]; !ok || r.round != g.round || g.state != StateOver {
+		log.Println("Dropping analysis for", r.c.Name, "from round", r.round)
+		return
+	}
+	r.c.sendSocketMsg(msg.Analysis, r.data)
+}
+
+// placedTiles returns every tile on this board as it is sent with msg.ProtocolSparse.
+func (b Board) placedTiles() []msg.PlacedTile {
+	var placed []msg.PlacedTile
+	for y, row := range b {
+		for x, t := range row {
+			if t != nil {
+				placed = append(placed, msg.PlacedTile{X: x, Y: y, Value: t.Value, ID: t.ID})
+			}
+		}
+	}
+	return placed
+}
@@ -0,0 +1,162 @@
+package game
+
+import (
+	"fmt"
+	"sort"
+	"time"
+	"unicode/utf8"
+
+	"github.com/kathrelkeld/speed-scrabble/msg"
+)
+
+// keptWords returns the words in this score which were counted towards the board.
+func (s *Score) keptWords() []Word {
+	var result []Word
+	for _, w := range s.Words {
+		if s.Valid.containsAll(w.tiles) {
+			result = append(result, w)
+		}
+	}
+	return result
+}
+
+// boundingBox returns the width and height of the smallest rectangle holding these tiles.
+func (s TileSet) boundingBox() Vec {
+	if len(s) == 0 {
+		return Vec{}
+	}
+	var min, max Vec
+	first := true
+	for v := range s {
+		if first || v.X < min.X {
+			min.X = v.X
+		}
+		if first || v.Y < min.Y {
+			min.Y = v.Y
+		}
+		if first || v.X > max.X {
+			max.X = v.X
+		}
+		if first || v.Y > max.Y {
+			max.Y = v.Y
+		}
+		first = false
+	}
+	return Vec{max.X - min.X + 1, max.Y - min.Y + 1}
+}
+
+// An awardRace tracks the best value seen for a single award and who holds it.
+type awardRace struct {
+	name    string
+	best    int
+	detail  string
+	players []string
+	lower   bool // Whether a lower value is better.
+}
+
+// enter records a player's value, keeping every player tied for the best.
+func (a *awardRace) enter(player string, value int, detail string) {
+	switch {
+	case len(a.players) == 0 || a.lower && value < a.best || !a.lower && value > a.best:
+		a.best = value
+		a.detail = detail
+		a.players = []string{player}
+	case value == a.best:
+		if a.players[len(a.players)-1] != player {
+			a.players = append(a.players, player)
+		}
+		if detail != a.detail {
+			a.detail += ", " + detail
+		}
+	}
+}
+
+// computeAwards returns the badges earned this round by the given players.
+// Only boards which used every tile compete for the most compact grid.  The longest word
+// counts letters, so a tile such as the Spanish CH counts twice there, but once for the
+// most tiles in one word.
+func computeAwards(names []string, scores []*Score, finishTimes []time.Duration, layout Layout) []msg.Award {
+	longest := &awardRace{name: "Longest word"}
+	highest := &awardRace{name: "Highest scoring word"}
+	mostTiles := &awardRace{name: "Most tiles in one word"}
+	compact := &awardRace{name: "Most compact grid", lower: true}
+	fastest := &awardRace{name: "Fastest finish", lower: true}
+
+	for i, s := range scores {
+		name := names[i]
+		if s != nil {
+			for _, w := range s.keptWords() {
+				longest.enter(name, utf8.RuneCountInString(w.Value), w.Value)
+				highest.enter(name, w.pts(layout), fmt.Sprintf("%v (%v pts)", w.Value, w.pts(layout)))
+				mostTiles.enter(name, len(w.tiles), fmt.Sprintf("%v (%v tiles)", w.Value, len(w.tiles)))
+			}
+			if s.Win {
+				box := s.Valid.boundingBox()
+				compact.enter(name, box.X*box.Y, fmt.Sprintf("%vx%v", box.X, box.Y))
+			}
+		}
+		if d := finishTimes[i]; d > 0 {
+			fastest.enter(name, int(d), d.Round(time.Second).String())
+		}
+	}
+
+	var awards []msg.Award
+	for _, a := range []*awardRace{longest, highest, mostTiles, compact, fastest} {
+		if len(a.players) == 0 {
+			continue
+		}
+		awards = append(awards, msg.Award{Name: a.name, Players: a.players, Detail: a.detail})
+	}
+	return awards
+}
+
+// results returns the final scores and awards for the round which just ended.
+func (g *Game) results() msg.ResultData {
+	var clients []*Client
+	for c := range g.clients {
+		clients = append(clients, c)
+	}
+	sort.Slice(clients, func(i, j int) bool { return clients[i].Name < clients[j].Name })
+
+	var names []string
+	var scores []*Score
+	var finishTimes []time.Duration
+	result := msg.ResultData{Rule: g.config.Rule.Name()}
+	for _, c := range clients {
+		s := g.lastScores[c]
+		names = append(names, c.Name)
+		scores = append(scores, s)
+		finishTimes = append(finishTimes, g.finishTimes[c])
+		if s != nil {
+			result.Players = append(result.Players, msg.PlayerResult{
+				Name:    c.Name,
+				Pts:     s.Pts - g.config.hintPenalty(c.hints),
+				Win:     s.Win,
+				Hints:   c.hints,
+				Repairs: c.repairs,
+			})
+		}
+	}
+	lower := g.config.Rule.LowerIsBetter()
+	sort.SliceStable(result.Players, func(i, j int) bool {
+		if lower {
+			return result.Players[i].Pts < result.Players[j].Pts
+		}
+		return result.Players[i].Pts > result.Players[j].Pts
+	})
+	result.Awards = computeAwards(names, scores, finishTimes, g.config.Layout)
+	for _, s := range scores {
+		if s == nil {
+			continue
+		}
+		for _, w := range s.Words {
+			if def, ok := g.config.Defs.short(w.Value); ok {
+				if result.Definitions == nil {
+					result.Definitions = make(map[string]string)
+				}
+				result.Definitions[w.Value] = def
+			}
+		}
+	}
+	return result
+}
@@ -1,54 +1,16 @@
 package game
 
 import (
-	"bufio"
 	"encoding/json"
 	"fmt"
 	"log"
-	"os"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/kathrelkeld/speed-scrabble/msg"
 )
 
-// Dictionary of valid words.
-type Dict map[string]struct{}
-
-var globalDict Dict
-
-// Called by server.
-func InitDictionary() {
-	globalDict = loadDictionary("game/sowpods.txt")
-}
-
-func loadDictionary(filename string) Dict {
-	d := make(map[string]struct{})
-	f, err := os.Open(filename)
-	if err != nil {
-		log.Println("Could not open dictionary list:", err)
-		panic("No dictionary!")
-		return d
-	}
-	defer f.Close()
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		word := scanner.Text()
-		d[word] = struct{}{}
-	}
-	if err := scanner.Err(); err != nil {
-		log.Println("Could not load dictionary:", err)
-		panic("No dictionary!")
-		return d
-	}
-	log.Println("Finished initializing dictionary from", filename)
-	return d
-}
-
-// verifyWord returns whether the given string is in the loaded dictionary.
-func verifyWord(w string) bool {
-	_, ok := globalDict[w]
-	return ok
-}
-
 // Vec is a struct used for vector calculations.
 type Vec struct {
 	X int
@@ -63,6 +25,14 @@ func (v Vec) scale(c int) Vec {
 	return Vec{v.X * c, v.Y * c}
 }
 
+// less orders coordinates by row and then by column.
+func (a Vec) less(b Vec) bool {
+	if a.Y != b.Y {
+		return a.Y < b.Y
+	}
+	return a.X < b.X
+}
+
 func (v Vec) String() string {
 	return fmt.Sprintf("(%v, %v)", v.X, v.Y)
 }
@@ -80,6 +50,45 @@ func (a TileSet) union(b TileSet) {
 	}
 }
 
+func (s TileSet) containsAll(b TileSet) bool {
+	for k := range b {
+		if !s.contains(k) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s TileSet) copy() TileSet {
+	result := make(TileSet, len(s))
+	result.union(s)
+	return result
+}
+
+// points returns the sum of the points of every tile in this set.
+func (s TileSet) points() int {
+	pts := 0
+	for _, t := range s {
+		pts += t.Points
+	}
+	return pts
+}
+
+// size returns the number of tiles in this set.
+func (s TileSet) size() int {
+	return len(s)
+}
+
+// sortedVecs returns the coordinates in this set in row then column order.
+func (s TileSet) sortedVecs() []Vec {
+	vs := make([]Vec, 0, len(s))
+	for v := range s {
+		vs = append(vs, v)
+	}
+	sort.Slice(vs, func(i, j int) bool { return vs[i].less(vs[j]) })
+	return vs
+}
+
 func (ts TileSet) MarshalJSON() ([]byte, error) {
 	var vs []Vec
 	for v := range ts {
@@ -96,7 +105,7 @@ type Board [][]*Tile
 func (b Board) setOfAllTiles() TileSet {
 	result := TileSet{}
 	for j := 0; j < len(b); j++ {
-		for i := 0; i < len(b[0]); i++ {
+		for i := 0; i < len(b[j]); i++ {
 			if b[j][i] != nil {
 				result[Vec{i, j}] = b[j][i]
 			}
@@ -109,8 +118,10 @@ type Word struct {
 	Start  Vec     // Coordinates of the word start.
 	End    Vec     // Coordinates of the word end.
 	Value  string  // Value of the word.
+	Reason string  // Why this word is not valid, if it is not.
 	isWord bool    // Whether this word is an actual word or not.
 	tiles  TileSet // Tiles in this word.
+	clash  TileSet // Tiles of another word which makes this one invalid, if any.
 }
 
 func (w Word) String() string {
@@ -119,12 +130,17 @@ func (w Word) String() string {
 
 type Score struct {
 	Win         bool     // Whether the board ends the game or not.
-	Pts         int      // The numerical score (lower is better).
-	Valid       TileSet  // Tiles which were part of no valid words.
+	Pts         int      // The numerical score, as counted by Rule.
+	Rule        string   // The name of the ScoringRule used.
+	WordList    string   // The name of the word list used.
+	DictVersion int      // The version of the word list used, which goes up with each reload.
+	Valid       TileSet  // Tiles which were part of valid words.
 	Invalid     TileSet  // Tiles which were part of no valid words.
 	Unconnected TileSet  // Tiles not part of the best scoring component.
 	Words       []Word   // Words found in the dictionary.
 	Nonwords    []Word   // Words not found in the dictionary.
+	Reasons     Reasons  // Why each tile was or was not counted.
+	Islands     []Island // Each island counted, when every island is scored.
 	msg         msg.Type // OK or Error message to send to player.
 }
 
@@ -132,6 +148,9 @@ func (s *Score) String() string {
 	result := "Score:"
 	result += fmt.Sprintf("\n    Win: %v", s.Win)
 	result += fmt.Sprintf("\n    Pts: %v", s.Pts)
+	result += fmt.Sprintf("\n    Rule: %v", s.Rule)
+	result += fmt.Sprintf("\n    WordList: %v", s.WordList)
+	result += fmt.Sprintf("\n    DictVersion: %v", s.DictVersion)
 	result += fmt.Sprintf("\n    Valid: %v", s.Valid)
 	result += fmt.Sprintf("\n    Unconnected: %v", s.Unconnected)
 	result += fmt.Sprintf("\n    Invalid: %v", s.Invalid)
@@ -146,10 +165,20 @@ func (s *Score) String() string {
 	return result
 }
 
+// An Island is a separately scored group of connected tiles.
+type Island struct {
+	Start Vec // Coordinates of the first tile of the island.
+	Pts   int // Points the rule gives the island, as if no other island were counted.
+}
+
 type Scorable struct {
+	kept        []Word // Words made by the valid tiles.
 	valid       TileSet
 	invalid     TileSet
+	removed     TileSet // Invalid tiles which were dropped so that the rest would be valid.
 	unconnected TileSet
+	islands     []*Scorable // Each component with valid tiles, when every island is scored.
+	clashing    TileSet     // Valid tiles of islands which break the word rules alongside others.
 	words       []Word
 	nonwords    []Word
 }
@@ -171,187 +200,254 @@ func (s *Scorable) String() string {
 }
 
 func (s *Scorable) score() int {
-	score := 0
-	for _, t := range s.valid {
-		score += t.Points
-	}
-	return score
+	return s.valid.points()
 }
 
-// findConnectedTiles puts all the tiles connected to this location into the given TileSet.
-func (s TileSet) findConnectedTiles(v Vec, connected TileSet) {
-	if connected.contains(v) {
-		return
-	}
-	if s.contains(v) {
-		connected[v] = s[v]
-		s.findConnectedTiles(v.add(Vec{1, 0}), connected)
-		s.findConnectedTiles(v.add(Vec{0, 1}), connected)
-		s.findConnectedTiles(v.add(Vec{-1, 0}), connected)
-		s.findConnectedTiles(v.add(Vec{0, -1}), connected)
-	}
+// weigh returns the weight of the tiles kept in this scorable.
+func (s *Scorable) weigh(weight subsetWeight) int {
+	return weight.of(s.valid, s.kept)
 }
 
-// findDisjointSets returns the disjoint word sections from the given TileSet.
+// findDisjointSets returns the disjoint word sections from the given TileSet, ordered by
+// their first tile.
 // E.g. if the board has two unconnected sections of tiles, this function returns a len 2 slice.
 func (s TileSet) findDisjointSets() []TileSet {
-	var comps []TileSet
-	used := make(TileSet)
-	for loc := range s {
-		if used.contains(loc) {
+	return s.toGrid().components()
+}
+
+// findWords returns every run of two or more tiles in the down and right directions, ordered
+// by starting coordinates, and checked by wc.  Runs which wc has already checked, with the
+// same tiles in the same place, are not checked again.
+func (s TileSet) findWords(wc *wordChecker) []Word {
+	var words []Word
+	var key []byte
+	g := s.toGrid()
+	g.eachRun(func(v, d Vec) {
+		key = g.runKey(key[:0], v, d)
+		w, ok := wc.runs[string(key)]
+		if !ok {
+			w = g.followWord(v, d)
+			wc.check(&w)
+			wc.runs[string(key)] = w
+		}
+		words = append(words, w)
+	})
+	wc.checkBoard(words)
+	return words
+}
+
+// runs returns every run of two or more tiles in the down and right directions, ordered by
+// starting coordinates, without checking whether they are words.
+func (s TileSet) runs() []Word {
+	var words []Word
+	g := s.toGrid()
+	g.eachRun(func(v, d Vec) {
+		words = append(words, g.followWord(v, d))
+	})
+	return words
+}
+
+// eachRun calls f with the start and direction of every run of two or more tiles in the down
+// and right directions, ordered by starting coordinates.
+func (g *grid) eachRun(f func(v, d Vec)) {
+	for i, t := range g.cells {
+		if t == nil {
 			continue
 		}
-		connected := make(TileSet)
-		s.findConnectedTiles(loc, connected)
-		comps = append(comps, connected)
-		used.union(connected)
+		v := g.vec(i)
+		for _, direction := range []Vec{{1, 0}, {0, 1}} {
+			if g.at(v.add(direction.scale(-1))) != nil {
+				// Ignore if this is not the start of a word.
+				continue
+			}
+			if g.at(v.add(direction)) == nil {
+				// Ignore "words" that are only one letter.
+				continue
+			}
+			f(v, direction)
+		}
 	}
-	return comps
 }
 
-// followWord
-func (comp TileSet) followWord(v Vec, d Vec) Word {
+// runKey appends to b a key which identifies the run starting at v in direction d by where
+// it is and which tiles it holds, as TileSet.key does for a set of tiles.
+func (g *grid) runKey(b []byte, v, d Vec) []byte {
+	b = strconv.AppendInt(b, int64(v.X), 10)
+	b = append(b, ',')
+	b = strconv.AppendInt(b, int64(v.Y), 10)
+	if d.X == 0 {
+		b = append(b, 'v')
+	}
+	for t := g.at(v); t != nil; v, t = v.add(d), g.at(v.add(d)) {
+		b = append(b, ';')
+		b = append(b, t.Value...)
+		b = append(b, ',')
+		b = strconv.AppendInt(b, int64(t.Points), 10)
+	}
+	return b
+}
+
+// followWord returns the word starting at v and continuing in direction d.
+func (g *grid) followWord(v Vec, d Vec) Word {
+	n := 0
+	for next := v; g.at(next) != nil; next = next.add(d) {
+		n++
+	}
 	w := Word{
 		Start: v,
-		Value: comp[v].Value,
-		tiles: make(TileSet),
+		End:   v.add(d.scale(n - 1)),
+		tiles: make(TileSet, n),
 	}
-	w.tiles[v] = comp[v]
-	prev := v
-	next := v.add(d)
-	for comp.contains(next) {
-		w.tiles[next] = comp[next]
-		w.Value += comp[next].Value
-		prev = next
-		next = next.add(d)
+	var value strings.Builder
+	for i, next := 0, v; i < n; i, next = i+1, next.add(d) {
+		t := g.at(next)
+		w.tiles[next] = t
+		value.WriteString(t.Value)
 	}
-
-	w.End = prev
-	w.isWord = verifyWord(w.Value)
+	w.Value = value.String()
 	return w
 }
 
-func (s TileSet) extractScorable() *Scorable {
+// extractScorable returns the heaviest scorable portion of these tiles, with words checked by
+// wc, reusing the scores of any unchanged components in the cache, which may be nil.
+// Only the best component is counted, unless allIslands is set, in which case every
+// component counts as a separate island.
+func (s TileSet) extractScorable(wc *wordChecker, weight subsetWeight, cache *scoreCache, allIslands bool) *Scorable {
 	overallSc := &Scorable{
 		valid:       make(TileSet),
 		invalid:     make(TileSet),
+		removed:     make(TileSet),
 		unconnected: make(TileSet),
 	}
 	comps := s.findDisjointSets()
 	fmt.Println("extracting scorable from multiple ", len(comps))
-	allScores := []*Scorable{}
-	for _, c := range comps {
-		allScores = append(allScores, c.extractScorableFromConnected())
-	}
+	allScores := cache.scoreComponents(wc, weight, comps)
 
 	if len(allScores) == 0 {
 		return overallSc
 	}
 
-	bestScore := allScores[0].score()
+	if allIslands {
+		clashes := clashingIslands(wc, weight, allScores)
+		overallSc.clashing = make(TileSet)
+		for i, sc := range allScores {
+			overallSc.merge(sc)
+			if clashes[i] {
+				overallSc.unconnected.union(sc.valid)
+				overallSc.clashing.union(sc.valid)
+				continue
+			}
+			overallSc.valid.union(sc.valid)
+			overallSc.kept = append(overallSc.kept, sc.kept...)
+			if len(sc.valid) > 0 {
+				overallSc.islands = append(overallSc.islands, sc)
+			}
+		}
+		return overallSc
+	}
+
+	bestScore := allScores[0].weigh(weight)
 	bestScorable := allScores[0]
 	for i := 1; i < len(allScores); i++ {
 		sc := allScores[i]
-		if pts := sc.score(); pts > bestScore {
+		pts := sc.weigh(weight)
+		if pts > bestScore || pts == bestScore && len(sc.valid) > len(bestScorable.valid) {
 			bestScore = pts
 			bestScorable, sc = sc, bestScorable
 		}
 		overallSc.unconnected.union(sc.valid)
-		overallSc.unconnected.union(sc.unconnected)
-		overallSc.invalid.union(sc.invalid)
-		overallSc.words = append(overallSc.words, sc.words...)
-		overallSc.nonwords = append(overallSc.nonwords, sc.nonwords...)
+		overallSc.merge(sc)
 	}
 
-	overallSc.valid = bestScorable.valid
-	overallSc.invalid.union(bestScorable.invalid)
-	overallSc.unconnected.union(bestScorable.unconnected)
-	overallSc.words = append(overallSc.words, bestScorable.words...)
-	overallSc.nonwords = append(overallSc.nonwords, bestScorable.nonwords...)
+	// Copy so that cached components are never modified.
+	overallSc.valid = bestScorable.valid.copy()
+	overallSc.kept = bestScorable.kept
+	overallSc.merge(bestScorable)
 	return overallSc
 }
 
-// Return the best scorable object that can be made from these connected tiles.
-func (s TileSet) extractScorableFromConnected() *Scorable {
-	fmt.Println("extracting scorable from a single component")
-	// Words must be 2 or more tiles.
-	sc := &Scorable{
-		valid:    make(TileSet),
-		invalid:  make(TileSet),
-		words:    []Word{},
-		nonwords: []Word{},
+// clashingIslands returns which of the scored islands do not count, as their words break the
+// word rules alongside those of heavier islands, such as by repeating one of them.  The
+// word rules are otherwise only checked within each island.
+func clashingIslands(wc *wordChecker, weight subsetWeight, islands []*Scorable) map[int]bool {
+	order := make([]int, len(islands))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return islands[order[i]].weigh(weight) > islands[order[j]].weigh(weight)
+	})
+
+	clashes := make(map[int]bool)
+	var counted []Word
+	for _, i := range order {
+		// Words are copied, as checkBoard marks them and cached islands must not change.
+		words := append(append([]Word(nil), counted...), islands[i].kept...)
+		wc.checkBoard(words)
+		if !onlyWords(words) {
+			clashes[i] = true
+			continue
+		}
+		counted = words
 	}
-	if len(s) <= 1 {
-		sc.invalid.union(s)
-		return sc
+	return clashes
+}
+
+// merge adds the unused tiles and all of the words of sc to this scorable.
+func (s *Scorable) merge(sc *Scorable) {
+	s.invalid.union(sc.invalid)
+	s.removed.union(sc.removed)
+	s.unconnected.union(sc.unconnected)
+	s.words = append(s.words, sc.words...)
+	s.nonwords = append(s.nonwords, sc.nonwords...)
+}
+
+// extractScorableFromConnected returns the heaviest scorable object that can be made from
+// these connected tiles.  Tiles removed to break up nonwords, or which were never part of a word,
+// are invalid; any other tiles left out of the best subset are unconnected.
+func (s TileSet) extractScorableFromConnected(wc *wordChecker, weight subsetWeight) *Scorable {
+	sc := &Scorable{
+		valid:       make(TileSet),
+		invalid:     make(TileSet),
+		removed:     make(TileSet),
+		unconnected: make(TileSet),
+		words:       []Word{},
+		nonwords:    []Word{},
 	}
 
-	// Check all words in the down and right directions.
-	for v := range s {
-		for _, direction := range []Vec{{1, 0}, {0, 1}} {
-			if s.contains(v.add(direction.scale(-1))) {
-				// Ignore if this is not the start of a word.
-				continue
-			}
-			if !s.contains(v.add(direction)) {
-				// Ignore "words" that are only one letter.
-				continue
-			}
-			w := s.followWord(v, direction)
-			if !w.isWord {
-				sc.invalid.union(w.tiles)
-				sc.nonwords = append(sc.nonwords, w)
-			} else {
-				sc.valid.union(w.tiles)
-				sc.words = append(sc.words, w)
-			}
+	inWord := make(TileSet)
+	for _, w := range s.findWords(wc) {
+		if !w.isWord {
+			sc.nonwords = append(sc.nonwords, w)
+		} else {
+			inWord.union(w.tiles)
+			sc.words = append(sc.words, w)
 		}
 	}
 
-	if len(sc.valid) == len(s) {
-		if len(sc.invalid) != 0 {
-			// If all tiles were used in valid words but there were also invalid words,
-			// brute force which tiles to drop.
-			bruteForce := sc.valid.bruteForceScorable(sc.invalid)
-			bruteForce.words = sc.words
-			bruteForce.nonwords = sc.nonwords
-			sc = bruteForce
+	best := s.bestSubset(wc, weight, scoreBudget)
+	if best == nil {
+		// Words must be 2 or more tiles, so no part of this component can be kept.
+		sc.invalid.union(s)
+		return sc
+	}
+	sc.valid = best.kept
+	sc.kept = best.words
+	sc.invalid.union(best.removed)
+	sc.removed.union(best.removed)
+	for v, t := range s {
+		if sc.valid.contains(v) || sc.invalid.contains(v) {
+			continue
 		}
-	} else {
-		// If some tiles in this component were not in any valid word, throw those out and
-		// call this function again on the subset.
-		subSc := sc.valid.extractScorable()
-		for v := range sc.invalid {
-			if !sc.valid.contains(v) {
-				subSc.invalid[v] = s[v]
-			}
+		if inWord.contains(v) {
+			sc.unconnected[v] = t
+		} else {
+			sc.invalid[v] = t
 		}
-		subSc.words = sc.words
-		subSc.nonwords = sc.nonwords
-		sc = subSc
 	}
 	return sc
 }
 
-func (s TileSet) bruteForceScorable(invalid TileSet) *Scorable {
-	fmt.Println("brute forcing a solution")
-	bestScore := 0
-	var bestChoice *Scorable
-	for elt := range invalid {
-		attempt := make(TileSet)
-		attempt.union(s)
-		delete(attempt, elt)
-		attemptScorable := attempt.extractScorable()
-		attemptScore := attemptScorable.score()
-		if attemptScore > bestScore {
-			bestScore = attemptScore
-			bestChoice = attemptScorable
-			bestChoice.invalid[elt] = s[elt]
-		}
-	}
-	return bestChoice
-}
-
 // compareTileValues will return false if board is not a subset of tiles served.
 // Takes in the tiles that have been served and the tiles on this board.
 func compareTileValues(sent []Tile, received TileSet) bool {
@@ -374,14 +470,30 @@ func compareTileValues(sent []Tile, received TileSet) bool {
 }
 
 // scoreBoard returns the overall score for this board given the tiles served.
+func (b Board) scoreBoard(tilesServed []Tile, cfg Config, cache *scoreCache) *Score {
+	return b.setOfAllTiles().scoreTiles(tilesServed, cfg, cache)
+}
+
+// Score returns the overall score for this board given the tiles served, counted as set
+// out in cfg.  This is how players' boards are scored, so a board which wins here would win
+// a round.
+func (b Board) Score(tilesServed []Tile, cfg Config) *Score {
+	return b.scoreBoard(tilesServed, cfg, nil)
+}
+
+// scoreTiles returns the overall score for a board with these tiles given the tiles served,
+// counted using the rule chosen in cfg.  The cache, if any, holds the player's previous
+// board.
 // This function is called by the client.
-func (b Board) scoreBoard(tilesServed []Tile) *Score {
-	boardSet := b.setOfAllTiles()
+func (boardSet TileSet) scoreTiles(tilesServed []Tile, cfg Config, cache *scoreCache) *Score {
 	fmt.Println("Tiles received:", boardSet)
 	fmt.Println("Tiles served:", tilesServed)
 	result := &Score{
-		Win: true,
-		msg: msg.Score,
+		Win:         true,
+		Rule:        cfg.Rule.Name(),
+		WordList:    cfg.WordList,
+		DictVersion: cfg.DictVersion,
+		msg:         msg.Score,
 	}
 
 	// Calculate score if board is empty.
@@ -389,24 +501,41 @@ func (b Board) scoreBoard(tilesServed []Tile) *Score {
 	for _, elt := range tilesServed {
 		maxPts += elt.Points
 	}
+	worstPts := cfg.Rule.Pts(&Scorable{valid: TileSet{}}, tilesServed, cfg.Layout)
 
 	// Find the best scoring component.
-	best := boardSet.extractScorable()
-	result.Pts = maxPts - best.score()
+	wc := newWordChecker(cfg.Dict, cfg.Words, cfg.Language)
+	wc.voted = cfg.Accepted
+	best := boardSet.extractScorable(wc, ruleWeight(cfg.Rule, cfg.Layout), cache, cfg.ScoreAllIslands)
+	leftover := maxPts - best.score()
+	result.Pts = cfg.Rule.Pts(best, tilesServed, cfg.Layout)
+	for _, island := range best.islands {
+		pts := cfg.Rule.Pts(island, tilesServed, cfg.Layout)
+		result.Islands = append(result.Islands, Island{island.valid.sortedVecs()[0], pts})
+	}
+	if n := len(best.islands); n > 1 {
+		penalty := cfg.IslandPenalty * (n - 1)
+		if cfg.Rule.LowerIsBetter() {
+			result.Pts += penalty
+		} else {
+			result.Pts -= penalty
+		}
+	}
 	result.Invalid = best.invalid
 	result.Valid = best.valid
 	result.Unconnected = best.unconnected
 	result.Words = best.words
 	result.Nonwords = best.nonwords
+	result.Reasons = best.explain(boardSet)
 
-	// A winning board has a score of 0.
-	if result.Pts != 0 {
+	// A winning board leaves no points unused.
+	if leftover != 0 {
 		result.Win = false
 		result.msg = msg.Invalid
-		if result.Pts < 0 {
+		if leftover < 0 {
 			log.Println("Impossible score: cheating suspected!")
 			result.msg = msg.Error
-			result.Pts = maxPts
+			result.Pts = worstPts
 		}
 	}
 	// A winning board must contain all the tiles served and no more.
@@ -415,7 +544,7 @@ func (b Board) scoreBoard(tilesServed []Tile) *Score {
 		if len(boardSet) > len(tilesServed) {
 			log.Println("Impossible number of tiles: cheating suspected!")
 			result.msg = msg.Error
-			result.Pts = maxPts
+			result.Pts = worstPts
 			return result
 		}
 	}
@@ -424,7 +553,7 @@ func (b Board) scoreBoard(tilesServed []Tile) *Score {
 		result.Win = false
 		log.Println("Impossibly mismatched tiles: cheating suspected!")
 		result.msg = msg.Error
-		result.Pts = maxPts
+		result.Pts = worstPts
 		return result
 	}
 	fmt.Println(result)
@@ -435,7 +564,7 @@ func (b Board) scoreBoard(tilesServed []Tile) *Score {
 func (b Board) String() string {
 	result := ""
 	for j := 0; j < len(b); j++ {
-		for i := 0; i < len(b[0]); i++ {
+		for i := 0; i < len(b[j]); i++ {
 			if b[j][i] == nil {
 				result += " "
 			} else {
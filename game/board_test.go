@@ -1,14 +1,36 @@
 package game
 
 import (
+	"bytes"
 	"fmt"
+	"io/ioutil"
 	"log"
+	"math/rand"
+	"os"
+	"path/filepath"
 	"reflect"
+	"runtime"
+	"sort"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/kathrelkeld/speed-scrabble/msg"
 )
 
+// testDict is the small word list used by every test.
+var testDict = mustLoadDictionary("test_data/dict.txt")
+
+// mustLoadDictionary returns the dictionary in the given file, panicking if it cannot be read.
+func mustLoadDictionary(filename string) Dictionary {
+	d, err := LoadDictionaryFile(filename)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
 func makeTestBoard(x, y int, letters ...string) Board {
-	globalDict = loadDictionary("test_data/dict.txt")
 	b := Board{}
 	if len(letters) != x*y {
 		log.Println("Letters count did not match given dimensions!")
@@ -28,6 +50,9 @@ func makeTestBoard(x, y int, letters ...string) Board {
 	return b
 }
 
+// makeTestTiles returns tiles with the given values, as if they had been served.
+var makeTestTiles = english.MakeTiles
+
 func testError(t *testing.T, got, expected interface{}, name string) {
 	t.Errorf("%s: Got %v; Expected %v", name, got, expected)
 }
@@ -155,7 +180,7 @@ var scoreTests = []struct {
 		board:   makeTestBoard(3, 3, "C", "A", "S", "", "", "O", "", "", "O"),
 		tiles:   []string{"C", "A", "O"},
 		score:   5,
-		invalid: []Vec{Vec{0, 0}, Vec{1, 0}, Vec{2, 0}, Vec{2, 1}, Vec{2, 2}},
+		invalid: []Vec{Vec{0, 0}, Vec{2, 1}, Vec{2, 2}}, // AS is kept.
 		nonw:    []string{"CAS", "SOO"},
 	},
 	{name: "Mismatched tile values, passing tiles",
@@ -169,7 +194,7 @@ var scoreTests = []struct {
 		board:   makeTestBoard(3, 3, "C", "A", "S", "", "", "O", "", "", "O"),
 		tiles:   []string{"C", "A", "T", "A", "A"},
 		score:   7,
-		invalid: []Vec{Vec{0, 0}, Vec{1, 0}, Vec{2, 0}, Vec{2, 1}, Vec{2, 2}},
+		invalid: []Vec{Vec{0, 0}, Vec{2, 1}, Vec{2, 2}}, // AS is kept.
 		nonw:    []string{"CAS", "SOO"},
 	},
 }
@@ -227,10 +252,10 @@ func TestVariousBoardScores(t *testing.T) {
 		tiles := []Tile{}
 		maxScore := 0
 		for _, elt := range input.tiles {
-			tiles = append(tiles, Tile{elt, pointValues[elt]})
+			tiles = append(tiles, Tile{Value: elt, Points: pointValues[elt]})
 			maxScore += pointValues[elt]
 		}
-		s := input.board.scoreBoard(tiles)
+		s := input.board.scoreBoard(tiles, DefaultConfig(testDict), nil)
 		if !cmp(s.Pts, input.score) {
 			testError(t, s.Pts, input.score, input.name+" - score")
 		}
@@ -251,3 +276,1085 @@ func TestVariousBoardScores(t *testing.T) {
 		}
 	}
 }
+
+// isValidSubset returns whether the given tiles are connected and every run is a word.
+func isValidSubset(s TileSet, wc *wordChecker) bool {
+	if len(s) < 2 || len(s.findDisjointSets()) != 1 {
+		return false
+	}
+	for _, w := range s.findWords(wc) {
+		if !w.isWord {
+			return false
+		}
+	}
+	return true
+}
+
+// pointsWeight weighs subsets by their points, as the default rule does.
+var pointsWeight = ruleWeight(leftoverRule{}, nil)
+
+// exhaustiveBestWeight tries every subset of the given tiles and returns the highest weight
+// of any valid subset.
+func exhaustiveBestWeight(s TileSet, wc *wordChecker, weight subsetWeight) int {
+	vs := s.sortedVecs()
+	best := 0
+	for mask := 1; mask < 1<<len(vs); mask++ {
+		sub := TileSet{}
+		for i, v := range vs {
+			if mask&(1<<i) != 0 {
+				sub[v] = s[v]
+			}
+		}
+		if !isValidSubset(sub, wc) {
+			continue
+		}
+		if w := weight.of(sub, sub.findWords(wc)); w > best {
+			best = w
+		}
+	}
+	return best
+}
+
+// makeRandomTileSet returns a partially filled x by y TileSet using letters from the test
+// dictionary.
+func makeRandomTileSet(r *rand.Rand, x, y int) TileSet {
+	const letters = "AAACCTTTPSSIFNMEEQO"
+	s := TileSet{}
+	for j := 0; j < y; j++ {
+		for i := 0; i < x; i++ {
+			if r.Intn(10) < 7 {
+				l := string(letters[r.Intn(len(letters))])
+				s[Vec{i, j}] = &Tile{Value: l, Points: pointValues[l]}
+			}
+		}
+	}
+	return s
+}
+
+func TestBestSubsetMatchesExhaustiveSearch(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	checkers := []*wordChecker{
+		newWordChecker(testDict, WordRules{}, nil),
+		newWordChecker(testDict, WordRulePresets["hard"], nil),
+		newWordChecker(testDict, WordRules{NoRepeats: true, BannedLetters: []string{"Q"}}, nil),
+	}
+	var rules []string
+	for name := range ScoringRules {
+		rules = append(rules, name)
+	}
+	sort.Strings(rules)
+	for i := 0; i < 300; i++ {
+		board := makeRandomTileSet(r, 4, 3)
+		wc := checkers[i%len(checkers)]
+		rule := rules[i%len(rules)]
+		weight := ruleWeight(ScoringRules[rule], classicLayout)
+		for _, c := range board.findDisjointSets() {
+			expected := exhaustiveBestWeight(c, wc, weight)
+			best := c.bestSubset(wc, weight, time.Minute)
+			got := 0
+			if best != nil {
+				got = best.pts
+				if !isValidSubset(best.kept, wc) {
+					t.Errorf("Board %v: best subset %v is not valid", c, best.kept)
+				}
+				if w := weight.of(best.kept, best.kept.findWords(wc)); best.pts != w {
+					testError(t, best.pts, w, rule+" - best subset weight")
+				}
+			}
+			if got != expected {
+				testError(t, got, expected, fmt.Sprintf("%s - best subset of %v", rule, c))
+			}
+		}
+	}
+}
+
+func TestBestSubsetIsDeterministic(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	for i := 0; i < 50; i++ {
+		board := makeRandomTileSet(r, 4, 3)
+		first := board.extractScorable(newWordChecker(testDict, WordRules{}, nil), pointsWeight, nil, false)
+		for j := 0; j < 5; j++ {
+			again := board.extractScorable(newWordChecker(testDict, WordRules{}, nil), pointsWeight, nil, false)
+			if !cmp(again.valid.sortedVecs(), first.valid.sortedVecs()) {
+				testError(t, again.valid, first.valid, "repeated valid tiles")
+			}
+			if !cmp(again.invalid.sortedVecs(), first.invalid.sortedVecs()) {
+				testError(t, again.invalid, first.invalid, "repeated invalid tiles")
+			}
+		}
+	}
+}
+
+func TestBestSubsetTieBreak(t *testing.T) {
+	// AT and AT are worth the same, so the earliest coordinates win.
+	b := makeTestBoard(3, 3, "A", "T", "", "", "", "", "A", "T", "")
+	sc := b.setOfAllTiles().extractScorable(newWordChecker(testDict, WordRules{}, nil), pointsWeight, nil, false)
+	if !cmpTileSetAndList(sc.valid, []Vec{{0, 0}, {1, 0}}) {
+		testError(t, sc.valid, []Vec{{0, 0}, {1, 0}}, "tie break")
+	}
+}
+
+func TestBestSubsetBudget(t *testing.T) {
+	board := makeRandomTileSet(rand.New(rand.NewSource(3)), 4, 3)
+	if best := board.bestSubset(newWordChecker(testDict, WordRules{}, nil), pointsWeight, 0); best != nil {
+		testError(t, best.kept, nil, "best subset with no budget")
+	}
+}
+
+func TestTileReasons(t *testing.T) {
+	tests := []struct {
+		name    string
+		board   Board
+		reasons map[Vec]string
+	}{
+		{name: "Dropped tile and disconnected component",
+			board: makeTestBoard(3, 3, "C", "A", "T", "", "E", "", "M", "E", ""),
+			reasons: map[Vec]string{
+				{0, 0}: "part of CAT",
+				{1, 1}: "dropped so that CAT would be valid",
+				{0, 2}: "in a disconnected component worth 1 pts less than the main one",
+				{1, 2}: "in a disconnected component worth 1 pts less than the main one",
+			},
+		},
+		{name: "Non-word",
+			board: makeTestBoard(3, 3, "C", "C", "T", "", "", "", "", "A", "T"),
+			reasons: map[Vec]string{
+				{0, 0}: "part of non-word CCT (not in the dictionary)",
+				{1, 2}: "part of AT",
+			},
+		},
+		{name: "Lone tile",
+			board: makeTestBoard(3, 1, "A", "", "Q"),
+			reasons: map[Vec]string{
+				{0, 0}: "not part of any word",
+			},
+		},
+	}
+	for _, input := range tests {
+		s := input.board.scoreBoard(nil, DefaultConfig(testDict), nil)
+		if len(s.Reasons) != len(input.board.setOfAllTiles()) {
+			testError(t, len(s.Reasons), len(input.board.setOfAllTiles()), input.name+" - reason count")
+		}
+		for v, expected := range input.reasons {
+			if got := s.Reasons[v]; got != expected {
+				testError(t, got, expected, fmt.Sprintf("%s - reason for %v", input.name, v))
+			}
+		}
+	}
+}
+
+func TestScoringRules(t *testing.T) {
+	// CAT and ACT use all but one A.
+	board := makeTestBoard(3, 3, "C", "A", "T", "", "C", "", "", "T", "")
+	tiles := makeTestTiles("C", "A", "T", "C", "T", "A")
+	tests := []struct {
+		rule  string
+		score int
+	}{
+		{"leftover", 1},
+		{"length-bonus", 9},
+		{"tile-count", 1},
+		{"letters-times-words", 10},
+	}
+	for _, input := range tests {
+		cfg := Config{Dict: testDict, Rule: ScoringRules[input.rule]}
+		s := board.scoreBoard(tiles, cfg, nil)
+		if s.Pts != input.score {
+			testError(t, s.Pts, input.score, input.rule+" - score")
+		}
+		if s.Rule != input.rule {
+			testError(t, s.Rule, input.rule, input.rule+" - rule name")
+		}
+	}
+
+	long := makeTestBoard(5, 1, "C", "A", "T", "A", "T")
+	dict := Dict{"CATAT": {}}
+	testDict.Each(func(w string) bool {
+		dict[w] = struct{}{}
+		return true
+	})
+	longTiles := makeTestTiles("C", "A", "T", "A", "T")
+	s := long.scoreBoard(longTiles, Config{Dict: dict, Rule: ScoringRules["length-bonus"]}, nil)
+	if s.Pts != 12 {
+		testError(t, s.Pts, 12, "length-bonus with a long word")
+	}
+}
+
+func TestScoringRulesChooseSubset(t *testing.T) {
+	// QI is worth more points, but CATS has more tiles, and only one of them can be kept.
+	board := makeTestBoard(6, 1, "Q", "I", "C", "A", "T", "S")
+	tiles := makeTestTiles("Q", "I", "C", "A", "T", "S")
+	qi := []Vec{{0, 0}, {1, 0}}
+	cats := []Vec{{2, 0}, {3, 0}, {4, 0}, {5, 0}}
+	tests := []struct {
+		rule  string
+		valid []Vec
+	}{
+		{"leftover", qi},
+		{"length-bonus", qi},
+		{"words", qi},
+		{"tile-count", cats},
+		{"letters-times-words", cats},
+	}
+	for _, input := range tests {
+		cfg := Config{Dict: testDict, Rule: ScoringRules[input.rule]}
+		s := board.scoreBoard(tiles, cfg, nil)
+		if !cmpTileSetAndList(s.Valid, input.valid) {
+			testError(t, s.Valid, input.valid, input.rule+" - valid tiles")
+		}
+	}
+}
+
+func TestScoreAllIslands(t *testing.T) {
+	// CAT along the top and a separate AT below it.
+	board := makeTestBoard(3, 3, "C", "A", "T", "", "", "", "A", "T", "")
+	tiles := makeTestTiles("C", "A", "T", "A", "T")
+	tests := []struct {
+		name    string
+		cfg     Config
+		pts     int
+		islands []Island
+	}{
+		{"best island only", Config{Dict: testDict, Rule: leftoverRule{}}, 2, nil},
+		{"all islands", Config{Dict: testDict, Rule: leftoverRule{}, ScoreAllIslands: true}, 0,
+			[]Island{{Vec{0, 0}, 2}, {Vec{0, 2}, 5}}},
+		{"all islands with penalty", Config{Dict: testDict, Rule: leftoverRule{}, ScoreAllIslands: true, IslandPenalty: 3}, 3,
+			[]Island{{Vec{0, 0}, 2}, {Vec{0, 2}, 5}}},
+		{"higher is better with penalty", Config{Dict: testDict, Rule: lengthBonusRule{}, ScoreAllIslands: true, IslandPenalty: 3}, 4,
+			[]Island{{Vec{0, 0}, 5}, {Vec{0, 2}, 2}}},
+	}
+	for _, input := range tests {
+		s := board.scoreBoard(tiles, input.cfg, nil)
+		if s.Pts != input.pts {
+			testError(t, s.Pts, input.pts, input.name+" - score")
+		}
+		if !reflect.DeepEqual(s.Islands, input.islands) {
+			testError(t, s.Islands, input.islands, input.name+" - islands")
+		}
+		if input.cfg.ScoreAllIslands && len(s.Unconnected) != 0 {
+			testError(t, s.Unconnected, TileSet{}, input.name+" - unconnected")
+		}
+	}
+
+	// The word rules apply across islands, so that only the best of two islands breaking
+	// them counts.
+	for _, input := range []struct {
+		name  string
+		board Board
+		words WordRules
+	}{
+		{"repeats", makeTestBoard(3, 3, "A", "T", "", "", "", "", "A", "T", ""), WordRules{NoRepeats: true}},
+		{"plurals", makeTestBoard(4, 3, "C", "A", "T", "S", "", "", "", "", "C", "A", "T", ""), WordRules{NoPlurals: true}},
+	} {
+		set := input.board.setOfAllTiles()
+		var tiles []Tile
+		for _, v := range set.sortedVecs() {
+			tiles = append(tiles, *set[v])
+		}
+		cfg := Config{Dict: testDict, Rule: leftoverRule{}, ScoreAllIslands: true, Words: input.words}
+		s := input.board.scoreBoard(tiles, cfg, nil)
+		if len(s.Islands) != 1 || s.Islands[0].Start != (Vec{0, 0}) {
+			testError(t, s.Islands, "the first island only", input.name+" - islands")
+		}
+		if r := s.Reasons[Vec{0, 2}]; !strings.Contains(r, "word rules") {
+			testError(t, r, "an island breaking the word rules", input.name+" - reason")
+		}
+	}
+}
+
+func TestPremiumSquares(t *testing.T) {
+	// CAT across the top and ACT down the middle, on the classic layout.
+	board := makeTestBoard(3, 3, "C", "A", "T", "", "C", "", "", "T", "")
+	tiles := makeTestTiles("C", "A", "T", "C", "T")
+	tests := []struct {
+		layout string
+		score  int
+	}{
+		// CAT is 5 and ACT is 5.
+		{"none", 10},
+		// CAT is tripled at (0, 0); ACT is doubled at (1, 1).
+		{"classic", 25},
+		// Neither word reaches a premium square.
+		{"sparse", 10},
+	}
+	for _, input := range tests {
+		cfg := Config{Dict: testDict, Rule: ScoringRules["words"], Layout: Layouts[input.layout]}
+		s := board.scoreBoard(tiles, cfg, nil)
+		if s.Pts != input.score {
+			testError(t, s.Pts, input.score, input.layout+" - score")
+		}
+	}
+
+	w := makeTestBoard(2, 1, "Q", "I").setOfAllTiles().findWords(newWordChecker(testDict, WordRules{}, nil))[0]
+	if got := w.pts(Layout{{0, 0}: TripleLetter, {1, 0}: DoubleWord}); got != 62 {
+		testError(t, got, 62, "letter and word premiums")
+	}
+}
+
+// makeCrossesTileSet returns n by n copies of a CAT and ACT cross, spaced apart so that each
+// is its own component.
+func makeCrossesTileSet(n int) TileSet {
+	cross := makeTestBoard(3, 3, "C", "A", "T", "", "C", "", "", "T", "").setOfAllTiles()
+	s := TileSet{}
+	for j := 0; j < n; j++ {
+		for i := 0; i < n; i++ {
+			for v, t := range cross {
+				s[v.add(Vec{4 * i, 4 * j})] = t
+			}
+		}
+	}
+	return s
+}
+
+func TestScoreCacheMatchesUncached(t *testing.T) {
+	r := rand.New(rand.NewSource(4))
+	cache := newScoreCache()
+	board := makeCrossesTileSet(3)
+	for i := 0; i < 30; i++ {
+		// Move a random tile to a random empty square.
+		from := board.sortedVecs()[r.Intn(len(board))]
+		to := Vec{r.Intn(12), r.Intn(12)}
+		if !board.contains(to) {
+			board[to] = board[from]
+			delete(board, from)
+		}
+		cached := board.extractScorable(newWordChecker(testDict, WordRules{}, nil), pointsWeight, cache, false)
+		uncached := board.extractScorable(newWordChecker(testDict, WordRules{}, nil), pointsWeight, nil, false)
+		for _, pair := range [][]TileSet{
+			{cached.valid, uncached.valid},
+			{cached.invalid, uncached.invalid},
+			{cached.unconnected, uncached.unconnected},
+		} {
+			if !cmp(pair[0].sortedVecs(), pair[1].sortedVecs()) {
+				testError(t, pair[0], pair[1], fmt.Sprintf("cached score after %v moves", i))
+			}
+		}
+	}
+}
+
+// benchmarkRescore scores one large board, almost all of it a single component, after
+// moving a tile in the middle of it back and forth.  Every run of tiles is a word, wherever
+// the tile is, as on a board a player is building.
+func benchmarkRescore(b *testing.B, cache *scoreCache) {
+	board := makeRandomTileSet(rand.New(rand.NewSource(1)), 16, 16)
+	from, to := board.sortedVecs()[len(board)/2], Vec{}
+	for board.contains(to) {
+		to = to.add(Vec{1, 0})
+	}
+	dict := Dict{}
+	for i := 0; i < 2; i++ {
+		for _, w := range board.runs() {
+			dict[w.Value] = struct{}{}
+		}
+		board[to] = board[from]
+		delete(board, from)
+		from, to = to, from
+	}
+	// Words are checked as in a server, with house rules over a compiled word list.
+	house, _ := LoadHouseRules("")
+	d := house.Dictionary("global", compileTestDAWG(b, dict))
+	board.extractScorable(newWordChecker(d, WordRules{}, nil), pointsWeight, cache, false)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		board[to] = board[from]
+		delete(board, from)
+		board.extractScorable(newWordChecker(d, WordRules{}, nil), pointsWeight, cache, false)
+		from, to = to, from
+	}
+}
+
+func BenchmarkRescoreUncached(b *testing.B) { benchmarkRescore(b, nil) }
+func BenchmarkRescoreCached(b *testing.B)   { benchmarkRescore(b, newScoreCache()) }
+
+func TestParseBoard(t *testing.T) {
+	served := makeTestTiles("C", "A", "T")
+	cfg := DefaultConfig(testDict)
+	cfg.BoardSize = Vec{4, 4}
+	cfg.MaxBoardTiles = 3
+	tests := []struct {
+		name     string
+		protocol int
+		data     string
+		tiles    []Vec
+		isErr    bool
+	}{
+		{name: "Sparse",
+			protocol: msg.ProtocolSparse,
+			data:     `[{"X":0,"Y":0,"Value":"C","ID":0},{"X":1,"Y":0,"Value":"A","ID":1}]`,
+			tiles:    []Vec{{0, 0}, {1, 0}},
+		},
+		{name: "Sparse duplicate coordinates",
+			protocol: msg.ProtocolSparse,
+			data:     `[{"X":0,"Y":0,"Value":"C","ID":0},{"X":0,"Y":0,"Value":"A","ID":1}]`,
+			isErr:    true,
+		},
+		{name: "Sparse duplicate tile",
+			protocol: msg.ProtocolSparse,
+			data:     `[{"X":0,"Y":0,"Value":"C","ID":0},{"X":1,"Y":0,"Value":"C","ID":0}]`,
+			isErr:    true,
+		},
+		{name: "Sparse out of bounds",
+			protocol: msg.ProtocolSparse,
+			data:     `[{"X":4,"Y":0,"Value":"C","ID":0}]`,
+			isErr:    true,
+		},
+		{name: "Sparse negative coordinates",
+			protocol: msg.ProtocolSparse,
+			data:     `[{"X":0,"Y":-1,"Value":"C","ID":0}]`,
+			isErr:    true,
+		},
+		{name: "Sparse unserved tile",
+			protocol: msg.ProtocolSparse,
+			data:     `[{"X":0,"Y":0,"Value":"C","ID":7}]`,
+			isErr:    true,
+		},
+		{name: "Sparse mismatched letter",
+			protocol: msg.ProtocolSparse,
+			data:     `[{"X":0,"Y":0,"Value":"Z","ID":0}]`,
+			isErr:    true,
+		},
+		{name: "Sparse too many tiles",
+			protocol: msg.ProtocolSparse,
+			data: `[{"X":0,"Y":0,"Value":"C","ID":0},{"X":1,"Y":0,"Value":"A","ID":1},` +
+				`{"X":2,"Y":0,"Value":"T","ID":2},{"X":3,"Y":0,"Value":"T","ID":3}]`,
+			isErr: true,
+		},
+		{name: "Grid",
+			protocol: msg.ProtocolGrid,
+			data:     `[[{"Value":"C","Points":3},null],[null,{"Value":"A","Points":1}]]`,
+			tiles:    []Vec{{0, 0}, {1, 1}},
+		},
+		{name: "Grid ragged",
+			protocol: msg.ProtocolGrid,
+			data:     `[[],[null,{"Value":"A","Points":1}]]`,
+			tiles:    []Vec{{1, 1}},
+		},
+		{name: "Grid empty",
+			protocol: msg.ProtocolGrid,
+			data:     `[]`,
+			tiles:    []Vec{},
+		},
+		{name: "Grid out of bounds",
+			protocol: msg.ProtocolGrid,
+			data:     `[[null,null,null,null,{"Value":"C","Points":3}]]`,
+			isErr:    true,
+		},
+	}
+	for _, input := range tests {
+		s, err := parseBoard([]byte(input.data), input.protocol, served, cfg)
+		if (err != nil) != input.isErr {
+			testError(t, err, input.isErr, input.name+" - error")
+			continue
+		}
+		if err == nil && !cmpTileSetAndList(s, input.tiles) {
+			testError(t, s, input.tiles, input.name+" - tiles")
+		}
+	}
+}
+
+func TestDictionaries(t *testing.T) {
+	dicts := map[string]Dictionary{
+		"map":  testDict,
+		"trie": newTrie(testDict),
+		"dawg": compileTestDAWG(t, testDict),
+	}
+	var expected []string
+	testDict.Each(func(w string) bool {
+		expected = append(expected, w)
+		return true
+	})
+	for name, d := range dicts {
+		for _, input := range []struct {
+			w                string
+			contains, prefix bool
+		}{
+			{"", false, true},
+			{"CA", false, true},
+			{"CAT", true, true},
+			{"CATS", true, true},
+			{"CATSS", false, false},
+			{"TAC", false, false},
+		} {
+			if got := d.Contains(input.w); got != input.contains {
+				testError(t, got, input.contains, name+" - contains "+input.w)
+			}
+			if got := d.HasPrefix(input.w); got != input.prefix {
+				testError(t, got, input.prefix, name+" - has prefix "+input.w)
+			}
+		}
+		var words []string
+		d.Each(func(w string) bool {
+			words = append(words, w)
+			return true
+		})
+		if !cmp(words, expected) {
+			testError(t, words, expected, name+" - each")
+		}
+		var first []string
+		d.Each(func(w string) bool {
+			first = append(first, w)
+			return len(first) < 2
+		})
+		if !cmp(first, expected[:2]) {
+			testError(t, first, expected[:2], name+" - each stops early")
+		}
+
+		board := makeTestBoard(3, 3, "C", "A", "T", "", "C", "", "", "T", "")
+		cfg := DefaultConfig(d)
+		s := board.scoreBoard(makeTestTiles("C", "A", "T", "C", "T"), cfg, nil)
+		if !s.Win {
+			testError(t, s.Win, true, name+" - scoring with the dictionary")
+		}
+	}
+}
+
+func TestWithPrefixes(t *testing.T) {
+	trie := newTrie(testDict)
+	dicts := map[string]Dictionary{"map": testDict, "trie": trie}
+	result := withPrefixes(dicts)
+	if dicts["map"] == nil || dicts["trie"] != trie || len(dicts) != 2 {
+		t.Errorf("withPrefixes changed the map it was given: %v", dicts)
+	}
+	if _, ok := result["map"].(*DAWG); !ok {
+		t.Errorf("word list held in a map became a %T", result["map"])
+	}
+	if result["trie"] != trie {
+		t.Errorf("word list held in a trie became a %T", result["trie"])
+	}
+	if got, expected := allWords(result["map"]), allWords(testDict); !cmp(got, expected) {
+		testError(t, got, expected, "compiled word list")
+	}
+}
+
+// compileTestDAWG returns the given dictionary compiled into a DAWG.
+func compileTestDAWG(t testing.TB, d Dictionary) *DAWG {
+	dawg, err := compileDAWG(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dawg
+}
+
+// allWords returns every word in d, in order.
+func allWords(d Dictionary) []string {
+	var words []string
+	d.Each(func(w string) bool {
+		words = append(words, w)
+		return true
+	})
+	return words
+}
+
+func TestDAWGMatchesWordList(t *testing.T) {
+	lists := map[string]Dictionary{
+		"test list":  testDict,
+		"empty":      Dict{},
+		"empty word": Dict{"": {}, "A": {}},
+		"prefixes":   Dict{"A": {}, "AB": {}, "ABC": {}, "ABD": {}, "B": {}, "BC": {}, "BD": {}},
+		"bytes":      Dict{"\x00": {}, "\xff\x00": {}, "É": {}, "ÉTÉ": {}},
+	}
+	if !testing.Short() {
+		lists["sowpods"] = mustLoadDictionary("sowpods.txt")
+		shipped, err := LoadWordList("sowpods")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !cmp(allWords(shipped), allWords(lists["sowpods"])) {
+			t.Errorf("sowpods.dawg is out of date; rebuild it with cmd/dictc")
+		}
+	}
+	for name, list := range lists {
+		dawg := compileTestDAWG(t, list)
+		expected := allWords(list)
+		if got := allWords(dawg); !cmp(got, expected) {
+			t.Errorf("%s: compiled words differ from the list", name)
+		}
+		trie := newTrie(list)
+		for _, w := range expected {
+			for i := 0; i <= len(w); i++ {
+				p := w[:i]
+				if dawg.Contains(p) != list.Contains(p) {
+					testError(t, dawg.Contains(p), list.Contains(p), name+" - contains "+p)
+				}
+				if dawg.HasPrefix(p) != trie.HasPrefix(p) {
+					testError(t, dawg.HasPrefix(p), trie.HasPrefix(p), name+" - has prefix "+p)
+				}
+			}
+			for _, extra := range []string{w + "A", w + "\x00", w + "ZZ"} {
+				if dawg.Contains(extra) != list.Contains(extra) {
+					testError(t, dawg.Contains(extra), list.Contains(extra), name+" - contains "+extra)
+				}
+			}
+		}
+	}
+
+	// Compiling is deterministic, and loading either format gives the same words.
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "dict.dawg")
+	var a, b bytes.Buffer
+	if err := WriteDAWG(&a, testDict); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteDAWG(&b, newTrie(testDict)); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(a.Bytes(), b.Bytes()) {
+		t.Errorf("compiling the same words twice gave different output")
+	}
+	if err := ioutil.WriteFile(filename, a.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	loaded := mustLoadDictionary(filename)
+	if _, ok := loaded.(*DAWG); !ok {
+		t.Errorf("loading a compiled dictionary gave a %T", loaded)
+	}
+	if got, expected := allWords(loaded), allWords(testDict); !cmp(got, expected) {
+		testError(t, got, expected, "loaded compiled dictionary")
+	}
+
+	// Damaged files are rejected.
+	data := a.Bytes()
+	for name, bad := range map[string][]byte{
+		"truncated":  data[:len(data)-2],
+		"no header":  data[:len(dawgMagic)+2],
+		"bad edge":   append(append([]byte{}, data[:len(data)-4]...), 0xff, 0xff, 0xff, 0xff),
+		"not a dawg": []byte("CAT\nDOG\n"),
+	} {
+		if _, err := readDAWG(bad); err == nil {
+			t.Errorf("%s: expected an error reading the compiled dictionary", name)
+		}
+	}
+}
+
+func TestLoadDictionaryErrors(t *testing.T) {
+	if _, err := LoadWordList(DefaultWordList); err != nil {
+		t.Errorf("loading the default word list: %v", err)
+	}
+	if _, err := LoadWordList("klingon"); err == nil {
+		t.Errorf("expected an error loading an unknown word list")
+	}
+	if _, err := LoadDictionaryFile("test_data/missing.txt"); err == nil {
+		t.Errorf("expected an error loading a missing file")
+	}
+	damaged := filepath.Join(t.TempDir(), "damaged.dawg")
+	if err := ioutil.WriteFile(damaged, []byte(dawgMagic+"\x00"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadDictionaryFile(damaged); err == nil {
+		t.Errorf("expected an error loading a damaged compiled dictionary")
+	}
+}
+
+func benchmarkLoadDictionary(b *testing.B, filename string) {
+	b.ReportAllocs()
+	var d Dictionary
+	for i := 0; i < b.N; i++ {
+		d = mustLoadDictionary(filename)
+	}
+	b.StopTimer()
+	runtime.GC()
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	b.ReportMetric(float64(m.HeapAlloc)/(1<<20), "heap-MB")
+	runtime.KeepAlive(d)
+}
+
+func BenchmarkLoadDictionaryText(b *testing.B) {
+	benchmarkLoadDictionary(b, "sowpods.txt")
+}
+
+func BenchmarkLoadDictionaryDAWG(b *testing.B) {
+	filename := filepath.Join(b.TempDir(), "sowpods.dawg")
+	f, err := os.Create(filename)
+	if err != nil {
+		b.Fatal(err)
+	}
+	if err := WriteDAWG(f, mustLoadDictionary("sowpods.txt")); err != nil {
+		b.Fatal(err)
+	}
+	f.Close()
+	runtime.GC()
+	b.ResetTimer()
+	benchmarkLoadDictionary(b, filename)
+}
+
+func TestHouseRules(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "house.json")
+	h, err := LoadHouseRules(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, edit := range []struct{ room, list, word string }{
+		{"", BanList, "cat"},
+		{"room", AllowList, "TAC"},
+		{"other", AllowList, "ZZZ"},
+	} {
+		if err := h.Add(edit.room, edit.list, edit.word); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := h.Add("", "maybe", "DOG"); err == nil {
+		t.Errorf("expected an error adding to an unknown list")
+	}
+	if err := h.Add("", BanList, " "); err == nil {
+		t.Errorf("expected an error adding no word")
+	}
+
+	d := h.Dictionary("room", testDict)
+	for w, expected := range map[string]bool{"CAT": false, "TAC": true, "ZZZ": false, "ACT": true} {
+		if got := d.Contains(w); got != expected {
+			testError(t, got, expected, "house rules - contains "+w)
+		}
+	}
+	if !d.HasPrefix("TA") || d.HasPrefix("ZZ") {
+		testError(t, d.HasPrefix("TA"), true, "house rules - has prefix")
+	}
+	var expected []string
+	for _, w := range allWords(testDict) {
+		if w != "CAT" {
+			expected = append(expected, w)
+		}
+	}
+	expected = append(expected, "TAC")
+	sort.Strings(expected)
+	if got := allWords(d); !cmp(got, expected) {
+		testError(t, got, expected, "house rules - each")
+	}
+
+	// Banned words say why, and cached scores follow changes to the lists.
+	board := makeTestBoard(3, 3, "C", "A", "T", "", "C", "", "", "T", "")
+	tiles := makeTestTiles("C", "A", "T", "C", "T")
+	cfg := DefaultConfig(d)
+	cache := newScoreCache()
+	s := board.scoreBoard(tiles, cfg, cache)
+	if len(s.Nonwords) != 1 || s.Nonwords[0].Reason != "banned by house rules" {
+		testError(t, s.Nonwords, "CAT (banned by house rules)", "house rules - nonwords")
+	}
+	if err := h.Remove("", BanList, "CAT"); err != nil {
+		t.Fatal(err)
+	}
+	if s := board.scoreBoard(tiles, cfg, cache); !s.Win {
+		testError(t, s.Win, true, "house rules - rescored after removing a ban")
+	}
+
+	reloaded, err := LoadHouseRules(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, expected := reloaded.Lists(), h.Lists(); !reflect.DeepEqual(got, expected) {
+		testError(t, got, expected, "house rules - reloaded")
+	}
+	if _, ok := reloaded.Lists()[""]; ok {
+		t.Errorf("house rules - empty lists should not be kept")
+	}
+}
+
+func TestLanguagePacks(t *testing.T) {
+	for name, lang := range LanguagePacks {
+		tiles := lang.newTiles()
+		if len(tiles) != lang.bagSize() {
+			testError(t, len(tiles), lang.bagSize(), name+" - bag size")
+		}
+		for l := range lang.Tiles {
+			if lang.Points[l] == 0 {
+				t.Errorf("%s: tile %v has no points", name, l)
+			}
+		}
+	}
+
+	spellings := []struct {
+		lang, word string
+		letters    []string
+	}{
+		{"spanish", "CHICO", []string{"CH", "I", "C", "O"}},
+		{"spanish", "PERRO", []string{"P", "E", "RR", "O"}},
+		{"spanish", "LLAMAÑ", []string{"LL", "A", "M", "A", "Ñ"}},
+		{"dutch", "IJS", []string{"IJ", "S"}},
+		{"german", "ÄRGER", []string{"Ä", "R", "G", "E", "R"}},
+		{"english", "CHILL", []string{"C", "H", "I", "L", "L"}},
+	}
+	for _, input := range spellings {
+		got := spell(input.word, LanguagePacks[input.lang].multiLetterTiles())
+		if !cmp(got, input.letters) {
+			testError(t, got, input.letters, input.lang+" - spelling "+input.word)
+		}
+	}
+
+	german := mustLoadDictionary("test_data/german.txt")
+	boards := []struct {
+		name   string
+		lang   string
+		dict   Dictionary
+		tiles  []string
+		win    bool
+		reason string
+	}{
+		{"digraph tile", "spanish", Dict{"CHICO": {}}, []string{"CH", "I", "C", "O"}, true, ""},
+		{"separate tiles", "spanish", Dict{"CHICO": {}}, []string{"C", "H", "I", "C", "O"}, false,
+			"spells CH without the CH tile"},
+		{"umlaut", "german", german, []string{"Ä", "R", "G", "E", "R"}, true, ""},
+		{"lower case list", "german", german, []string{"Ü", "B", "E", "R"}, true, ""},
+		{"english letters", "english", Dict{"CHI": {}}, []string{"C", "H", "I"}, true, ""},
+	}
+	for _, input := range boards {
+		board := makeTestBoard(len(input.tiles), 1, input.tiles...)
+		cfg := DefaultConfig(input.dict)
+		cfg.Language = LanguagePacks[input.lang]
+		s := board.scoreBoard(makeTestTiles(input.tiles...), cfg, nil)
+		if s.Win != input.win {
+			testError(t, s.Win, input.win, input.name+" - win")
+		}
+		if !input.win && (len(s.Nonwords) != 1 || s.Nonwords[0].Reason != input.reason) {
+			testError(t, s.Nonwords, input.reason, input.name+" - reason")
+		}
+	}
+}
+
+func TestWordRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   WordRules
+		board   Board
+		tiles   []string
+		score   int
+		invalid []Vec
+		words   []string
+		nonw    []string
+		reasons []string
+	}{
+		{name: "Minimum length",
+			rules:   WordRules{MinLength: 3},
+			board:   makeTestBoard(3, 2, "C", "A", "T", "", "S", ""),
+			tiles:   []string{"C", "A", "T", "S"},
+			score:   1,
+			invalid: []Vec{{1, 1}},
+			words:   []string{"CAT"},
+			nonw:    []string{"AS"},
+			reasons: []string{"shorter than 3 letters"},
+		},
+		{name: "Repeated word",
+			rules:   WordRules{NoRepeats: true},
+			board:   makeTestBoard(3, 3, "C", "A", "T", "A", "", "", "T", "", ""),
+			tiles:   []string{"C", "A", "T", "A", "T"},
+			score:   2,
+			invalid: []Vec{{0, 1}, {0, 2}},
+			words:   []string{"CAT"},
+			nonw:    []string{"CAT"},
+			reasons: []string{"repeats CAT"},
+		},
+		{name: "Repeated word allowed",
+			board: makeTestBoard(3, 3, "C", "A", "T", "A", "", "", "T", "", ""),
+			tiles: []string{"C", "A", "T", "A", "T"},
+			words: []string{"CAT", "CAT"},
+		},
+		{name: "Plural",
+			rules:   WordRules{NoPlurals: true},
+			board:   makeTestBoard(4, 3, "C", "A", "T", "S", "A", "", "", "", "T", "", "", ""),
+			tiles:   []string{"C", "A", "T", "S", "A", "T"},
+			score:   1,
+			invalid: []Vec{{3, 0}},
+			words:   []string{"CAT"},
+			nonw:    []string{"CATS"},
+			reasons: []string{"plural of CAT"},
+		},
+		{name: "Banned letter",
+			rules:   WordRules{BannedLetters: []string{"q"}},
+			board:   makeTestBoard(2, 1, "Q", "I"),
+			tiles:   []string{"Q", "I"},
+			score:   11,
+			invalid: []Vec{{0, 0}, {1, 0}},
+			nonw:    []string{"QI"},
+			reasons: []string{"uses banned letter Q"},
+		},
+	}
+	for _, input := range tests {
+		cfg := DefaultConfig(testDict)
+		cfg.Words = input.rules
+		s := input.board.scoreBoard(makeTestTiles(input.tiles...), cfg, nil)
+		if s.Pts != input.score {
+			testError(t, s.Pts, input.score, input.name+" - score")
+		}
+		if !cmpTileSetAndList(s.Invalid, input.invalid) {
+			testError(t, s.Invalid, input.invalid, input.name+" - invalid")
+		}
+		if !cmpWordsAndList(s.Words, input.words) {
+			testError(t, s.Words, input.words, input.name+" - words")
+		}
+		if !cmpWordsAndList(s.Nonwords, input.nonw) {
+			testError(t, s.Nonwords, input.nonw, input.name+" - nonwords")
+		}
+		var reasons []string
+		for _, w := range s.Nonwords {
+			reasons = append(reasons, w.Reason)
+		}
+		if len(input.reasons) > 0 && !cmp(reasons, input.reasons) {
+			testError(t, reasons, input.reasons, input.name+" - reasons")
+		}
+	}
+}
+
+// The benchmarks below use boards of up to 16 by 16 squares, the most a player may have.
+
+func benchmarkFindDisjointSets(b *testing.B, side int) {
+	s := makeRandomTileSet(rand.New(rand.NewSource(1)), side, side)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.findDisjointSets()
+	}
+}
+
+func BenchmarkFindDisjointSets8(b *testing.B)  { benchmarkFindDisjointSets(b, 8) }
+func BenchmarkFindDisjointSets12(b *testing.B) { benchmarkFindDisjointSets(b, 12) }
+func BenchmarkFindDisjointSets16(b *testing.B) { benchmarkFindDisjointSets(b, 16) }
+
+func benchmarkFindWords(b *testing.B, side int) {
+	s := makeRandomTileSet(rand.New(rand.NewSource(1)), side, side)
+	wc := newWordChecker(testDict, WordRules{}, nil)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.findWords(wc)
+	}
+}
+
+func BenchmarkFindWords8(b *testing.B)  { benchmarkFindWords(b, 8) }
+func BenchmarkFindWords12(b *testing.B) { benchmarkFindWords(b, 12) }
+func BenchmarkFindWords16(b *testing.B) { benchmarkFindWords(b, 16) }
+
+// benchmarkExtractScorable scores a board of n by n valid crosses.
+func benchmarkExtractScorable(b *testing.B, n int) {
+	s := makeCrossesTileSet(n)
+	wc := newWordChecker(testDict, WordRules{}, nil)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.extractScorable(wc, pointsWeight, nil, false)
+	}
+}
+
+func BenchmarkExtractScorable2(b *testing.B) { benchmarkExtractScorable(b, 2) }
+func BenchmarkExtractScorable3(b *testing.B) { benchmarkExtractScorable(b, 3) }
+func BenchmarkExtractScorable4(b *testing.B) { benchmarkExtractScorable(b, 4) }
+
+// floodFill returns every tile connected to v, one step at a time.
+func floodFill(s TileSet, v Vec) TileSet {
+	connected := TileSet{v: s[v]}
+	queue := []Vec{v}
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+		for _, d := range []Vec{{1, 0}, {0, 1}, {-1, 0}, {0, -1}} {
+			n := next.add(d)
+			if s.contains(n) && !connected.contains(n) {
+				connected[n] = s[n]
+				queue = append(queue, n)
+			}
+		}
+	}
+	return connected
+}
+
+func TestFindDisjointSetsMatchesFloodFill(t *testing.T) {
+	r := rand.New(rand.NewSource(5))
+	for _, n := range []int{1, 4, 8, 16} {
+		s := makeRandomTileSet(r, n, n)
+		comps := s.findDisjointSets()
+		total := 0
+		for _, c := range comps {
+			total += len(c)
+			first := c.sortedVecs()[0]
+			if expected := floodFill(s, first); !cmp(c.sortedVecs(), expected.sortedVecs()) {
+				testError(t, c, expected, fmt.Sprintf("component of %v in %v by %v tiles", first, n, n))
+			}
+		}
+		if total != len(s) {
+			testError(t, total, len(s), fmt.Sprintf("tiles in components of %v by %v tiles", n, n))
+		}
+	}
+	if comps := (TileSet{}).findDisjointSets(); len(comps) != 0 {
+		testError(t, len(comps), 0, "components of no tiles")
+	}
+}
+
+func TestFindAnagrams(t *testing.T) {
+	ws := func(w string, pts int) msg.WordScore { return msg.WordScore{Word: w, Pts: pts} }
+	for _, input := range []struct {
+		name     string
+		req      msg.AnagramRequest
+		expected []msg.AnagramGroup
+	}{
+		{
+			name: "whole rack",
+			req:  msg.AnagramRequest{Rack: "tacs"},
+			expected: []msg.AnagramGroup{
+				{Length: 4, Words: []msg.WordScore{ws("CATS", 6)}},
+				{Length: 3, Words: []msg.WordScore{ws("ACT", 5), ws("CAT", 5)}},
+				{Length: 2, Words: []msg.WordScore{ws("AS", 2), ws("AT", 2)}},
+			},
+		},
+		{
+			name: "sorted by points",
+			req:  msg.AnagramRequest{Rack: "QIAS", Sort: "points"},
+			expected: []msg.AnagramGroup{
+				{Length: 2, Words: []msg.WordScore{ws("QI", 11), ws("AS", 2), ws("IS", 2)}},
+			},
+		},
+		{
+			name: "pattern",
+			req:  msg.AnagramRequest{Rack: "CTS", Pattern: "?A?"},
+			expected: []msg.AnagramGroup{
+				{Length: 3, Words: []msg.WordScore{ws("CAT", 5)}},
+			},
+		},
+		{
+			name: "blank",
+			req:  msg.AnagramRequest{Rack: "C?T"},
+			expected: []msg.AnagramGroup{
+				{Length: 3, Words: []msg.WordScore{ws("ACT", 4), ws("CAT", 4)}},
+				{Length: 2, Words: []msg.WordScore{ws("AT", 1)}},
+			},
+		},
+		{
+			name: "pattern alone",
+			req:  msg.AnagramRequest{Rack: "Z", Pattern: "CAT"},
+		},
+	} {
+		for name, d := range map[string]Dictionary{"map": testDict, "dawg": compileTestDAWG(t, testDict)} {
+			got, err := FindAnagrams(d, nil, input.req)
+			if err != nil {
+				t.Errorf("%v - %v: %v", name, input.name, err)
+				continue
+			}
+			if !cmp(got.Groups, input.expected) {
+				testError(t, got.Groups, input.expected, name+" - "+input.name)
+			}
+		}
+	}
+
+	for _, req := range []msg.AnagramRequest{
+		{Rack: "CA1"},
+		{Rack: "CAT", Pattern: "C-T"},
+		{Rack: "CAT", Sort: "length"},
+		{Rack: "???"},
+		{Rack: "ABCDEFGHIJKLMNOPQRSTUVWXYZABCDE"},
+	} {
+		if _, err := FindAnagrams(testDict, nil, req); err == nil {
+			t.Errorf("expected an error finding anagrams for %+v", req)
+		}
+	}
+
+	// Multi-letter tiles must spell words with the tile, rather than its letters.
+	spanish := newTrie(Dict{"CHE": {}, "ECHE": {}})
+	got, err := FindAnagrams(spanish, LanguagePacks["spanish"], msg.AnagramRequest{Rack: "CH E E"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []msg.AnagramGroup{
+		{Length: 3, Words: []msg.WordScore{ws("ECHE", 7)}},
+		{Length: 2, Words: []msg.WordScore{ws("CHE", 6)}},
+	}
+	if !cmp(got.Groups, expected) {
+		testError(t, got.Groups, expected, "spanish")
+	}
+	if got, _ := FindAnagrams(spanish, LanguagePacks["spanish"], msg.AnagramRequest{Rack: "C,H,E"}); len(got.Groups) != 0 {
+		testError(t, got.Groups, "no words", "spanish with separate C and H")
+	}
+}
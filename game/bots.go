@@ -0,0 +1,50 @@
+package game
+
+import (
+	"log"
+	"sort"
+
+	"github.com/kathrelkeld/speed-scrabble/msg"
+)
+
+// addBot starts a computer player of the given level, which joins this game as any other
+// player would.
+func (g *Game) addBot(c *Client, level string) {
+	if g.ga.StartBot == nil {
+		c.sendSocketMsg(msg.Error, "Error: this server has no bots")
+		return
+	}
+	conn, err := g.ga.StartBot(level, msg.JoinGameData{
+		WordList:      g.config.WordList,
+		Language:      g.config.Language.orEnglish().Name,
+		Rule:          g.config.Rule.Name(),
+		Layout:        g.config.LayoutName,
+		Words:         g.config.WordsName,
+		Islands:       g.config.ScoreAllIslands,
+		IslandPenalty: g.config.IslandPenalty,
+	})
+	if err != nil {
+		c.sendSocketMsg(msg.Error, "Error: "+err.Error())
+		return
+	}
+	log.Println("Game", g.Name, "adding", level, "bot for", c.Name)
+	g.ga.StartNewClient(conn)
+}
+
+// removeBot asks one of the computer players in this game, the last by name, to leave it.
+func (g *Game) removeBot(c *Client) {
+	var bots []*Client
+	for other := range g.clients {
+		if other.bot {
+			bots = append(bots, other)
+		}
+	}
+	if len(bots) == 0 {
+		c.sendSocketMsg(msg.Error, "Error: there are no bots in this game")
+		return
+	}
+	sort.Slice(bots, func(i, j int) bool { return bots[i].Name < bots[j].Name })
+	b := bots[len(bots)-1]
+	log.Println("Game", g.Name, "removing", b.Name, "for", c.Name)
+	b.sendSocketMsg(msg.Exit, "removed by "+c.Name)
+}
@@ -0,0 +1,68 @@
+package game
+
+import (
+	"strconv"
+)
+
+// A scoreCache remembers how each component of a player's last scored board was scored, so
+// that components which have not changed since then are not searched again.  Only the
+// components of the most recent board are kept.  It also remembers every run of tiles the
+// player has made, so that a large component which changed only in part checks just its new
+// runs.
+type scoreCache struct {
+	comps   map[string]*Scorable
+	runs    map[string]Word // Checked runs of tiles, as held by the word checker.
+	version int             // Version of the house rules the components were scored with.
+}
+
+func newScoreCache() *scoreCache {
+	return &scoreCache{comps: make(map[string]*Scorable), runs: make(map[string]Word)}
+}
+
+// key returns a string which identifies exactly which tiles are at which coordinates.
+func (s TileSet) key() string {
+	var b []byte
+	for _, v := range s.sortedVecs() {
+		b = strconv.AppendInt(b, int64(v.X), 10)
+		b = append(b, ',')
+		b = strconv.AppendInt(b, int64(v.Y), 10)
+		b = append(b, ',')
+		b = append(b, s[v].Value...)
+		b = append(b, ',')
+		b = strconv.AppendInt(b, int64(s[v].Points), 10)
+		b = append(b, ';')
+	}
+	return string(b)
+}
+
+// scoreComponents returns the scorable portion of each of the given components, reusing
+// any that were scored for the previous board.  A nil cache scores every component.
+// A cache must always be used with the same word rules, dictionary and weight, though the
+// house rules over the dictionary may change.
+func (cache *scoreCache) scoreComponents(wc *wordChecker, weight subsetWeight, comps []TileSet) []*Scorable {
+	var result []*Scorable
+	if cache == nil {
+		for _, c := range comps {
+			result = append(result, c.extractScorableFromConnected(wc, weight))
+		}
+		return result
+	}
+	if cache.version != wc.version {
+		cache.comps = nil
+		cache.runs = make(map[string]Word)
+		cache.version = wc.version
+	}
+	wc.runs = cache.runs
+	next := make(map[string]*Scorable)
+	for _, c := range comps {
+		k := c.key()
+		sc, ok := cache.comps[k]
+		if !ok {
+			sc = c.extractScorableFromConnected(wc, weight)
+		}
+		next[k] = sc
+		result = append(result, sc)
+	}
+	cache.comps = next
+	return result
+}
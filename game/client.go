@@ -3,6 +3,7 @@ package game
 import (
 	"encoding/json"
 	"log"
+	"time"
 
 	"github.com/gorilla/websocket"
 
@@ -17,12 +18,22 @@ type Client struct {
 	ga        *GameAssigner
 	game      *Game
 	servedCnt int
+	cache     *scoreCache // Scores of the components of the last board submitted.
+	board     TileSet     // The last board submitted this round, if any.
+	score     *Score      // The score of board.
+	protocol  int         // How this player sends boards, e.g. msg.ProtocolSparse.
+	bot       bool        // Whether this player is a computer.
+	hints     int         // Number of hints taken this round.
+	lastHint  time.Time   // When the last hint was given.
+	hinting   bool        // Whether a hint is being searched for.
+	repairs   int         // Number of board repairs this round.
 }
 
 // Close is used to request the Client exit gracefully.
+// Must only be called from the goroutine of the Client's game, if it has one.
 func (c *Client) Close() {
 	if c.game != nil {
-		c.game.toGameChan <- MsgFromClient{msg.Exit, c, nil}
+		c.game.send(MsgFromClient{msg.Exit, c, nil})
 	}
 	c.conn.Close()
 }
@@ -31,13 +42,16 @@ func (c *Client) Close() {
 // Must be run as a separate go routine.
 // Will return when c.conn is closed through c.Close() or on websocket error.
 // The first message byte is always the type, followed by JSON data.
+// The game joined is kept here, as c.game belongs to the game's own goroutine.
 func (c *Client) readSocketMsgs() {
+	var game *Game
 	for {
 		_, b, err := c.conn.ReadMessage()
 		if err != nil {
 			log.Println("Error reading socket message; closing client", err)
-			if c.game != nil {
-				c.Close()
+			if game != nil {
+				game.send(MsgFromClient{msg.Exit, c, nil})
+				c.conn.Close()
 			}
 			return
 		}
@@ -45,16 +59,52 @@ func (c *Client) readSocketMsgs() {
 		b = b[1:]           // Any remaining bytes are JSON encoded data.
 
 		log.Println("Got websocket message of type", t)
-		if t == msg.JoinGame {
-			c.ga.NewGameChan <- MsgGameRequest{c}
-		} else if c.game != nil {
-			c.game.toGameChan <- MsgFromClient{t, c, b}
+		if t == msg.JoinGame && game == nil {
+			req := c.readJoinGame(b)
+			c.ga.NewGameChan <- req
+			a := <-req.joined
+			if a.err != nil {
+				c.sendSocketMsg(msg.Error, "Error: "+a.err.Error())
+				continue
+			}
+			game = a.game
+			game.send(MsgFromClient{msg.JoinGame, c, nil})
+		} else if game != nil && t != msg.JoinGame {
+			game.send(MsgFromClient{t, c, b})
 		} else {
 			log.Println("Ignoring websocket message of type", t)
 		}
 	}
 }
 
+// readJoinGame records the name and board protocol requested by this player, and returns
+// their request for a game.  Players which only send a name get msg.ProtocolGrid.
+func (c *Client) readJoinGame(b []byte) MsgGameRequest {
+	c.protocol = msg.ProtocolGrid
+	var req msg.JoinGameData
+	if err := json.Unmarshal(b, &req); err != nil {
+		// The player's name is optional.
+		json.Unmarshal(b, &c.Name)
+		return MsgGameRequest{C: c, joined: make(chan gameAssignment, 1)}
+	}
+	c.Name = req.Name
+	c.bot = req.Bot
+	if req.Protocol == msg.ProtocolSparse {
+		c.protocol = msg.ProtocolSparse
+	}
+	return MsgGameRequest{
+		C:             c,
+		WordList:      req.WordList,
+		Language:      req.Language,
+		Rule:          req.Rule,
+		Layout:        req.Layout,
+		Words:         req.Words,
+		Islands:       req.Islands,
+		IslandPenalty: req.IslandPenalty,
+		joined:        make(chan gameAssignment, 1),
+	}
+}
+
 // sendSocketMsg sends a websocket message of the given type with the given data.
 // The data must be marshallable into JSON.
 func (c *Client) sendSocketMsg(t msg.Type, d interface{}) {
@@ -88,15 +138,18 @@ func (c *Client) addTile() {
 	}
 }
 
-// ScoreMarshalledBoard takes a JSON board and returns a score for that board.
-func (c *Client) ScoreMarshalledBoard(d []byte) *Score {
-	var board Board
-	err := json.Unmarshal(d, &board)
+// ScoreMarshalledBoard takes a JSON board, in the format negotiated with this player, and
+// returns a score for that board.
+func (c *Client) ScoreMarshalledBoard(d []byte) (*Score, error) {
+	served := c.game.tiles[:c.servedCnt]
+	tiles, err := parseBoard(d, c.protocol, served, c.game.config)
 	if err != nil {
-		log.Println("error:", err)
-		return nil
+		log.Println("Rejected board:", err)
+		return nil, err
 	}
-	return board.scoreBoard(c.game.tiles[:c.servedCnt])
+	c.board = tiles
+	c.score = tiles.scoreTiles(served, c.game.config, c.cache)
+	return c.score, nil
 }
 
 func (c *Client) SendScore(s *Score) {
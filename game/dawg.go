@@ -0,0 +1,299 @@
+package game
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// A DAWG is a Dictionary stored as a minimized directed acyclic word graph, read directly from
+// its compiled form, so that loading it needs no work beyond reading the file.
+//
+// The compiled form is dawgMagic, a uint32 of flags, a uint32 count of edges, and then each
+// edge as a uint32, all little endian.  The edges leaving a node are stored together in order
+// of their labels, with the last one marked, and the edges leaving the root come first.
+// Each edge holds its label in the lowest 8 bits, then whether a word ends after it, then
+// whether it is the last edge of its node, and then the index of the first edge leaving the
+// node it leads to, which is 0 if there are none.
+type DAWG struct {
+	data   []byte
+	nEdges int
+	empty  bool // Whether the empty string is a word.
+}
+
+const (
+	dawgMagic      = "SSDAWG1\n"
+	dawgHeaderSize = len(dawgMagic) + 8
+
+	dawgFlagEmpty = 1 << 0
+
+	dawgFinal     = 1 << 8
+	dawgLast      = 1 << 9
+	dawgChildBits = 10
+	dawgMaxEdges  = 1 << (32 - dawgChildBits)
+)
+
+// isDAWG returns whether data starts like a compiled DAWG.
+func isDAWG(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(dawgMagic))
+}
+
+// readDAWG returns the DAWG compiled into data, which it keeps using.
+func readDAWG(data []byte) (*DAWG, error) {
+	if !isDAWG(data) || len(data) < dawgHeaderSize {
+		return nil, errors.New("not a compiled dictionary")
+	}
+	flags := binary.LittleEndian.Uint32(data[len(dawgMagic):])
+	n := int(binary.LittleEndian.Uint32(data[len(dawgMagic)+4:]))
+	if len(data) != dawgHeaderSize+4*n {
+		return nil, fmt.Errorf("compiled dictionary has %v bytes of edges, expected %v",
+			len(data)-dawgHeaderSize, 4*n)
+	}
+	d := &DAWG{data: data, nEdges: n, empty: flags&dawgFlagEmpty != 0}
+	for i := 0; i < n; i++ {
+		if c := d.edge(i) >> dawgChildBits; int(c) >= n {
+			return nil, fmt.Errorf("edge %v leads to edge %v of %v", i, c, n)
+		}
+	}
+	if n > 0 && d.edge(n-1)&dawgLast == 0 {
+		return nil, errors.New("compiled dictionary ends partway through a node")
+	}
+	return d, nil
+}
+
+// edge returns the ith edge.
+func (d *DAWG) edge(i int) uint32 {
+	return binary.LittleEndian.Uint32(d.data[dawgHeaderSize+4*i:])
+}
+
+// follow returns the edge leaving the node whose edges start at first which is labelled b.
+func (d *DAWG) follow(first int, b byte) (uint32, bool) {
+	for i := first; i < d.nEdges; i++ {
+		e := d.edge(i)
+		if byte(e) == b {
+			return e, true
+		}
+		if e&dawgLast != 0 {
+			break
+		}
+	}
+	return 0, false
+}
+
+// find returns the last edge along the path spelling p, if there is one.
+func (d *DAWG) find(p string) (uint32, bool) {
+	var e uint32
+	first := 0
+	for i := 0; i < len(p); i++ {
+		if i > 0 {
+			first = int(e >> dawgChildBits)
+			if first == 0 {
+				return 0, false
+			}
+		}
+		var ok bool
+		if e, ok = d.follow(first, p[i]); !ok {
+			return 0, false
+		}
+	}
+	return e, true
+}
+
+// Contains returns whether w is a word.
+func (d *DAWG) Contains(w string) bool {
+	if w == "" {
+		return d.empty
+	}
+	e, ok := d.find(w)
+	return ok && e&dawgFinal != 0
+}
+
+// HasPrefix returns whether any word starts with p.
+func (d *DAWG) HasPrefix(p string) bool {
+	if p == "" {
+		return d.empty || d.nEdges > 0
+	}
+	_, ok := d.find(p)
+	return ok
+}
+
+// Each calls f with every word in sorted order, until f returns false.
+func (d *DAWG) Each(f func(w string) bool) {
+	if d.empty && !f("") {
+		return
+	}
+	if d.nEdges > 0 {
+		d.each(0, nil, f)
+	}
+}
+
+// each calls f with every word continuing prefix through the edges starting at first.
+// Returns false once f has returned false.
+func (d *DAWG) each(first int, prefix []byte, f func(w string) bool) bool {
+	for i := first; i < d.nEdges; i++ {
+		e := d.edge(i)
+		w := append(prefix, byte(e))
+		if e&dawgFinal != 0 && !f(string(w)) {
+			return false
+		}
+		if c := int(e >> dawgChildBits); c != 0 && !d.each(c, w, f) {
+			return false
+		}
+		if e&dawgLast != 0 {
+			break
+		}
+	}
+	return true
+}
+
+// A dawgNode is a node of a DAWG while it is being built.
+type dawgNode struct {
+	final    bool
+	labels   []byte
+	children []*dawgNode
+	id       int // Set once the node is registered, and so will not change.
+	first    int // Index of the node's first edge, once laid out.
+}
+
+// signature identifies a registered node by its finality and its edges.
+func (n *dawgNode) signature() string {
+	var b strings.Builder
+	if n.final {
+		b.WriteByte('!')
+	}
+	for i, c := range n.children {
+		b.WriteByte(n.labels[i])
+		b.WriteString(strconv.Itoa(c.id))
+		b.WriteByte(',')
+	}
+	return b.String()
+}
+
+// A dawgBuilder minimizes a DAWG as words are added in sorted order, replacing each node with
+// an identical registered one once no more words can pass through it.
+type dawgBuilder struct {
+	root      dawgNode
+	register  map[string]*dawgNode
+	unchecked []*dawgNode // The nodes along the last word, which may still change.
+	last      string
+}
+
+// add adds w, which must sort after every word added before.
+func (db *dawgBuilder) add(w string) error {
+	if w < db.last || w == db.last && w != "" {
+		return fmt.Errorf("word %q is out of order or repeated", w)
+	}
+	common := 0
+	for common < len(w) && common < len(db.last) && w[common] == db.last[common] {
+		common++
+	}
+	db.minimize(common)
+	n := &db.root
+	if common > 0 {
+		n = db.unchecked[common-1]
+	}
+	for i := common; i < len(w); i++ {
+		next := &dawgNode{}
+		n.labels = append(n.labels, w[i])
+		n.children = append(n.children, next)
+		db.unchecked = append(db.unchecked, next)
+		n = next
+	}
+	n.final = true
+	db.last = w
+	return nil
+}
+
+// minimize registers every unchecked node below the given depth.
+func (db *dawgBuilder) minimize(depth int) {
+	for i := len(db.unchecked) - 1; i >= depth; i-- {
+		n := db.unchecked[i]
+		key := n.signature()
+		if existing, ok := db.register[key]; ok {
+			parent := &db.root
+			if i > 0 {
+				parent = db.unchecked[i-1]
+			}
+			parent.children[len(parent.children)-1] = existing
+			continue
+		}
+		n.id = len(db.register) + 1
+		db.register[key] = n
+	}
+	db.unchecked = db.unchecked[:depth]
+}
+
+// compileDAWG returns every word of d in a DAWG, compiled as WriteDAWG writes it.
+func compileDAWG(d Dictionary) (*DAWG, error) {
+	var b bytes.Buffer
+	if err := WriteDAWG(&b, d); err != nil {
+		return nil, err
+	}
+	return readDAWG(b.Bytes())
+}
+
+// WriteDAWG writes every word of d to w as a compiled DAWG.
+func WriteDAWG(w io.Writer, d Dictionary) error {
+	db := &dawgBuilder{register: make(map[string]*dawgNode)}
+	var err error
+	d.Each(func(word string) bool {
+		err = db.add(word)
+		return err == nil
+	})
+	if err != nil {
+		return err
+	}
+	db.minimize(0)
+
+	// Lay out the edges of each node breadth first, starting with the root.
+	var order []*dawgNode
+	nEdges := 0
+	queue := []*dawgNode{&db.root}
+	seen := map[*dawgNode]bool{&db.root: true}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		if len(n.children) == 0 {
+			continue
+		}
+		n.first = nEdges
+		nEdges += len(n.children)
+		order = append(order, n)
+		for _, c := range n.children {
+			if !seen[c] {
+				seen[c] = true
+				queue = append(queue, c)
+			}
+		}
+	}
+	if nEdges > dawgMaxEdges {
+		return fmt.Errorf("dictionary needs %v edges, more than the limit of %v", nEdges, dawgMaxEdges)
+	}
+
+	buf := make([]byte, dawgHeaderSize+4*nEdges)
+	copy(buf, dawgMagic)
+	var flags uint32
+	if db.root.final {
+		flags |= dawgFlagEmpty
+	}
+	binary.LittleEndian.PutUint32(buf[len(dawgMagic):], flags)
+	binary.LittleEndian.PutUint32(buf[len(dawgMagic)+4:], uint32(nEdges))
+	for _, n := range order {
+		for i, c := range n.children {
+			e := uint32(n.labels[i]) | uint32(c.first)<<dawgChildBits
+			if c.final {
+				e |= dawgFinal
+			}
+			if i == len(n.children)-1 {
+				e |= dawgLast
+			}
+			binary.LittleEndian.PutUint32(buf[dawgHeaderSize+4*(n.first+i):], e)
+		}
+	}
+	_, err = w.Write(buf)
+	return err
+}
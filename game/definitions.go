@@ -0,0 +1,62 @@
+package game
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Definitions holds the meaning of words, by word.
+type Definitions map[string]string
+
+// shortDefinitionLen is the most characters of a definition sent with the results of a round.
+const shortDefinitionLen = 80
+
+// DefinitionsFile returns where the definitions for the given word list file are kept: next
+// to it, with the extension ".defs".
+func DefinitionsFile(wordListFile string) string {
+	return strings.TrimSuffix(wordListFile, filepath.Ext(wordListFile)) + ".defs"
+}
+
+// LoadDefinitions returns the definitions in the given file, where each line holds a word,
+// a tab and its definition.  Blank lines and lines starting with # are skipped.
+func LoadDefinitions(filename string) (Definitions, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	defs := make(Definitions)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(line, "\t", 2)
+		if len(parts) < 2 {
+			continue
+		}
+		defs[strings.ToUpper(strings.TrimSpace(parts[0]))] = strings.TrimSpace(parts[1])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return defs, nil
+}
+
+// Define returns the definition of w, if there is one.  Nil Definitions define nothing.
+func (d Definitions) Define(w string) (string, bool) {
+	def, ok := d[strings.ToUpper(w)]
+	return def, ok
+}
+
+// short returns the definition of w cut down to at most shortDefinitionLen characters.
+func (d Definitions) short(w string) (string, bool) {
+	def, ok := d.Define(w)
+	if r := []rune(def); len(r) > shortDefinitionLen {
+		def = strings.TrimSpace(string(r[:shortDefinitionLen-1])) + "…"
+	}
+	return def, ok
+}
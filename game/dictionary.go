@@ -0,0 +1,241 @@
+package game
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed" // For the built in word lists.
+	"fmt"
+	"io/ioutil"
+	"log"
+	"sort"
+	"strings"
+)
+
+//go:embed sowpods.dawg
+var sowpodsDAWG []byte
+
+// wordLists holds the word lists built into the server, by name.  Compiled lists are
+// rebuilt from their text versions with cmd/dictc.
+var wordLists = map[string][]byte{
+	"sowpods": sowpodsDAWG,
+}
+
+// DefaultWordList names the built in word list used unless another is chosen.
+const DefaultWordList = "sowpods"
+
+// LoadWordLists returns every built in word list, by name.
+func LoadWordLists() (map[string]Dictionary, error) {
+	dicts := make(map[string]Dictionary)
+	for name := range wordLists {
+		d, err := LoadWordList(name)
+		if err != nil {
+			return nil, err
+		}
+		dicts[name] = d
+	}
+	return dicts, nil
+}
+
+// LoadWordList returns the built in word list with the given name.
+func LoadWordList(name string) (Dictionary, error) {
+	data, ok := wordLists[name]
+	if !ok {
+		return nil, fmt.Errorf("no word list named %q", name)
+	}
+	d, err := parseDictionary(data)
+	if err != nil {
+		return nil, fmt.Errorf("word list %q: %v", name, err)
+	}
+	log.Println("Finished initializing dictionary", name)
+	return d, nil
+}
+
+// LoadDictionaryFile returns the dictionary in the given file, which is either a word list
+// with one word per line or a compiled DAWG.
+func LoadDictionaryFile(filename string) (Dictionary, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	d, err := parseDictionary(data)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", filename, err)
+	}
+	log.Println("Finished initializing dictionary from", filename)
+	return d, nil
+}
+
+// parseDictionary returns the dictionary in data, which is either a word list with one word
+// per line or a compiled DAWG.  Words in a list are upper cased, and blank lines skipped.
+func parseDictionary(data []byte) (Dictionary, error) {
+	if isDAWG(data) {
+		return readDAWG(data)
+	}
+	d := make(Dict)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		if w := strings.ToUpper(strings.TrimSpace(scanner.Text())); w != "" {
+			d[w] = struct{}{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// A Dictionary is a set of valid words.
+type Dictionary interface {
+	// Contains returns whether w is a word.
+	Contains(w string) bool
+	// HasPrefix returns whether any word starts with p.
+	HasPrefix(p string) bool
+	// Each calls f with every word in sorted order, until f returns false.
+	Each(f func(w string) bool)
+}
+
+// Dict is a Dictionary of valid words held in a map.
+type Dict map[string]struct{}
+
+// Contains returns whether w is a word.
+func (d Dict) Contains(w string) bool {
+	_, ok := d[w]
+	return ok
+}
+
+// HasPrefix returns whether any word starts with p.  This checks every word, so a Trie
+// should be preferred for prefix queries.
+func (d Dict) HasPrefix(p string) bool {
+	for w := range d {
+		if strings.HasPrefix(w, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Each calls f with every word in sorted order, until f returns false.
+func (d Dict) Each(f func(w string) bool) {
+	words := make([]string, 0, len(d))
+	for w := range d {
+		words = append(words, w)
+	}
+	sort.Strings(words)
+	for _, w := range words {
+		if !f(w) {
+			return
+		}
+	}
+}
+
+// withPrefixes returns the given dictionaries, with any held in a map compiled into a DAWG,
+// so that every one answers prefix queries quickly.  The given map is left unchanged.
+// Lists too large to compile are held in a Trie instead.
+func withPrefixes(dicts map[string]Dictionary) map[string]Dictionary {
+	result := make(map[string]Dictionary, len(dicts))
+	for name, d := range dicts {
+		if m, ok := d.(Dict); ok {
+			compiled, err := compileDAWG(m)
+			if err != nil {
+				log.Println("Holding word list", name, "in a trie:", err)
+				result[name] = newTrie(m)
+				continue
+			}
+			d = compiled
+		}
+		result[name] = d
+	}
+	return result
+}
+
+// A Trie is a Dictionary which stores words by their shared prefixes.
+type Trie struct {
+	root trieNode
+}
+
+// A trieNode is reached by a prefix, and has a child for each byte which may follow it.
+type trieNode struct {
+	isWord   bool
+	labels   []byte // Sorted, so that words are visited in order.
+	children []*trieNode
+}
+
+// newTrie returns a Trie holding every word in the given dictionary.
+func newTrie(d Dictionary) *Trie {
+	t := &Trie{}
+	d.Each(func(w string) bool {
+		t.Add(w)
+		return true
+	})
+	return t
+}
+
+// child returns the node following n by byte b, or nil if there is none.
+func (n *trieNode) child(b byte) *trieNode {
+	i := sort.Search(len(n.labels), func(i int) bool { return n.labels[i] >= b })
+	if i < len(n.labels) && n.labels[i] == b {
+		return n.children[i]
+	}
+	return nil
+}
+
+// Add adds w to the trie.
+func (t *Trie) Add(w string) {
+	n := &t.root
+	for i := 0; i < len(w); i++ {
+		b := w[i]
+		next := n.child(b)
+		if next == nil {
+			next = &trieNode{}
+			j := sort.Search(len(n.labels), func(j int) bool { return n.labels[j] >= b })
+			n.labels = append(n.labels, 0)
+			copy(n.labels[j+1:], n.labels[j:])
+			n.labels[j] = b
+			n.children = append(n.children, nil)
+			copy(n.children[j+1:], n.children[j:])
+			n.children[j] = next
+		}
+		n = next
+	}
+	n.isWord = true
+}
+
+// find returns the node reached by the prefix p, or nil if no word starts with p.
+func (t *Trie) find(p string) *trieNode {
+	n := &t.root
+	for i := 0; i < len(p) && n != nil; i++ {
+		n = n.child(p[i])
+	}
+	return n
+}
+
+// Contains returns whether w is a word.
+func (t *Trie) Contains(w string) bool {
+	n := t.find(w)
+	return n != nil && n.isWord
+}
+
+// HasPrefix returns whether any word starts with p.
+func (t *Trie) HasPrefix(p string) bool {
+	n := t.find(p)
+	return n != nil && (n.isWord || len(n.children) > 0)
+}
+
+// Each calls f with every word in sorted order, until f returns false.
+func (t *Trie) Each(f func(w string) bool) {
+	t.root.each(nil, f)
+}
+
+// each calls f with every word below n, which is reached by prefix.  Returns false once f
+// has returned false.
+func (n *trieNode) each(prefix []byte, f func(w string) bool) bool {
+	if n.isWord && !f(string(prefix)) {
+		return false
+	}
+	for i, c := range n.children {
+		if !c.each(append(prefix, n.labels[i]), f) {
+			return false
+		}
+	}
+	return true
+}
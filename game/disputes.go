@@ -0,0 +1,149 @@
+package game
+
+import (
+	"log"
+
+	"github.com/kathrelkeld/speed-scrabble/msg"
+)
+
+// A wordVote is a vote by the other players on whether a challenged word should count.
+type wordVote struct {
+	word       string
+	challenger *Client
+	voters     map[*Client]bool // Every player asked to vote.
+	ballots    map[*Client]bool // Each vote cast so far, true to accept the word.
+}
+
+// challenge starts a vote on accepting a word which is only invalid because it is not in the
+// dictionary, and which is on the challenger's last board.  Only one vote runs at a time.
+func (g *Game) challenge(c *Client, word string) {
+	if g.vote != nil {
+		c.sendSocketMsg(msg.Error, "Error: a vote on "+g.vote.word+" is already running")
+		return
+	}
+	if !c.score.disputable(word) {
+		c.sendSocketMsg(msg.Error, "Error: "+word+" is not a word on your board that can be challenged")
+		return
+	}
+	v := &wordVote{
+		word:       word,
+		challenger: c,
+		voters:     make(map[*Client]bool),
+		ballots:    make(map[*Client]bool),
+	}
+	for other := range g.clients {
+		if other != c && !other.bot {
+			v.voters[other] = true
+		}
+	}
+	if len(v.voters) == 0 {
+		c.sendSocketMsg(msg.Error, "Error: there is no one else to vote")
+		return
+	}
+	log.Println("Game", g.Name, "voting on", word, "challenged by", c.Name)
+	g.vote = v
+	g.sendToAllClientsExcept(c, msg.Vote, msg.VoteData{Word: word, Challenger: c.Name})
+}
+
+// disputable returns whether w is one of the non-words of this score which is only invalid
+// because it is not in the dictionary.  A nil score has none.
+func (s *Score) disputable(w string) bool {
+	if s == nil {
+		return false
+	}
+	for _, n := range s.Nonwords {
+		if n.Value == w && n.Reason == notInDictionary {
+			return true
+		}
+	}
+	return false
+}
+
+// castBallot records a player's vote on the running vote, and ends it once the result is
+// certain.
+func (g *Game) castBallot(c *Client, b msg.BallotData) {
+	if g.vote == nil || b.Word != g.vote.word || !g.vote.voters[c] {
+		c.sendSocketMsg(msg.Error, "Error: no vote on "+b.Word+" for you to answer")
+		return
+	}
+	g.vote.ballots[c] = b.Accept
+	g.tallyVote()
+}
+
+// dropVoter removes a player who left the game from the running vote, if any.
+func (g *Game) dropVoter(c *Client) {
+	if g.vote == nil {
+		return
+	}
+	delete(g.vote.voters, c)
+	delete(g.vote.ballots, c)
+	g.tallyVote()
+}
+
+// tallyVote ends the running vote once more than half of the voters have accepted the word,
+// or once that can no longer happen.
+func (g *Game) tallyVote() {
+	v := g.vote
+	result := msg.VoteResultData{Word: v.word}
+	for _, accept := range v.ballots {
+		if accept {
+			result.For++
+		} else {
+			result.Against++
+		}
+	}
+	n := len(v.voters)
+	switch {
+	case 2*result.For > n:
+		result.Accepted = true
+	case 2*result.Against >= n:
+	default:
+		return
+	}
+	g.vote = nil
+	log.Printf("Game %v vote on %v: %v for, %v against", g.Name, v.word, result.For, result.Against)
+	g.sendToAllClients(msg.VoteResult, result)
+	if result.Accepted {
+		g.acceptWord(v.word)
+	}
+}
+
+// acceptWord makes w count for the rest of this game, rescores every board with it, and
+// records it as a candidate for the house allow list.
+func (g *Game) acceptWord(w string) {
+	if g.config.Accepted == nil {
+		g.config.Accepted = make(map[string]bool)
+	}
+	g.config.Accepted[w] = true
+	log.Println("Game", g.Name, "accepted", w, "as a candidate for the house allow list")
+	if g.ga.house != nil {
+		if err := g.ga.house.Add(g.Name, CandidateList, w); err != nil {
+			log.Println("Could not record candidate word:", err)
+		}
+	}
+	g.rescore()
+}
+
+// rescore scores every player's last board again with the current settings, and sends the
+// results again if the round is over.  A board which now wins while the round is running
+// finishes the round, just as if it had been verified.
+func (g *Game) rescore() {
+	over := g.state == StateOver
+	for c := range g.clients {
+		// Cached components were scored before the change.
+		c.cache = newScoreCache()
+		if c.board == nil {
+			continue
+		}
+		c.score = c.board.scoreTiles(g.tiles[:c.servedCnt], g.config, c.cache)
+		c.SendScore(c.score)
+		if g.lastScores[c] != nil {
+			g.lastScores[c] = c.score
+		} else if c.score.Win && g.state == StateRunning {
+			g.finish(c, c.score)
+		}
+	}
+	if over {
+		g.sendToAllClients(msg.Result, g.results())
+	}
+}
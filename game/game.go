@@ -1,7 +1,9 @@
 package game
 
 import (
+	"encoding/json"
 	"log"
+	"time"
 
 	"github.com/kathrelkeld/speed-scrabble/msg"
 )
@@ -24,11 +26,18 @@ type Game struct {
 	tiles           []Tile
 	clients         map[*Client]bool
 	lastScores      map[*Client]*Score
+	finishTimes     map[*Client]time.Duration
+	roundStart      time.Time
 	state           gameState
+	config          Config
 	startingTileCnt int
 	ga              *GameAssigner
+	vote            *wordVote // The running vote on a challenged word, if any.
+	round           int       // Counts the rounds ended, so that late analyses and hints are dropped.
 
 	toGameChan chan MsgFromClient
+	analyses   chan analysisResult
+	hints      chan hintResult
 	quit       chan struct{}
 }
 
@@ -46,17 +55,26 @@ func (g *Game) Close() {
 	for c := range g.clients {
 		c.Close()
 	}
-	close(g.toGameChan)
 	close(g.quit)
 }
 
-// Add player adds the given player to this game.
+// send passes a message to the game, unless it has closed.
+func (g *Game) send(m MsgFromClient) {
+	select {
+	case g.toGameChan <- m:
+	case <-g.quit:
+	}
+}
+
+// Add player adds the given player to this game.  Players join through toGameChan, so that
+// this runs on the game's own goroutine.
 // TODO handle whether to send tiles based on game state.
 func (g *Game) AddPlayer(c *Client) {
 	log.Println("runGame: Adding client to game")
 	g.clients[c] = false
-	c.sendSocketMsg(msg.PlayerJoined, nil)
+	c.sendSocketMsg(msg.PlayerJoined, msg.PlayerJoinedData{Protocol: c.protocol})
 	c.game = g
+	c.cache = newScoreCache()
 }
 
 // sendGameInfo sends information about this game to each player.
@@ -65,7 +83,14 @@ func (g *Game) sendGameInfo() {
 	for c := range g.clients {
 		names = append(names, c.Name)
 	}
-	info := msg.GameInfoData{g.Name, names}
+	info := msg.GameInfoData{
+		GameName:    g.Name,
+		PlayerNames: names,
+		Premiums:    g.config.Layout.squares(),
+		WordList:    g.config.WordList,
+		Language:    g.config.Language.orEnglish().Name,
+		Words:       g.config.WordsName,
+	}
 	for c := range g.clients {
 		c.sendSocketMsg(msg.GameInfo, info)
 	}
@@ -105,6 +130,29 @@ func (g *Game) sendToAllClientsExcept(exc *Client, t msg.Type, d interface{}) {
 	}
 }
 
+// endRound sends the final scores and awards to all players.
+func (g *Game) endRound() {
+	g.sendToAllClients(msg.Result, g.results())
+	g.state = StateOver
+	g.round++
+	g.startAnalyses()
+	log.Println("Game is over!")
+}
+
+// finish records the winning board of the first player to finish, and asks every other
+// player for their board.
+func (g *Game) finish(c *Client, score *Score) {
+	g.lastScores[c] = score
+	g.finishTimes[c] = time.Since(g.roundStart)
+	g.resetClientReply()
+	g.state = StateWaitingScores
+	g.clients[c] = true
+	g.sendToAllClientsExcept(c, msg.SendBoard, nil)
+	if g.allClientsTrue() {
+		g.endRound()
+	}
+}
+
 // Run handles incoming messages and directs gameflow.
 func (g *Game) Run() {
 	for {
@@ -112,12 +160,27 @@ func (g *Game) Run() {
 		case cm := <-g.toGameChan:
 			log.Println("Game got client message of type:", cm.Type)
 			switch cm.Type {
+			case msg.JoinGame:
+				g.AddPlayer(cm.C)
 			case msg.RoundReady:
 				// Player indicating that they want to start a new round.
 				if g.state != StateWaitingRoundReady {
 					// If game is not waiting, start a new round and start waiting.
-					g.tiles = newTiles()
+					// Any reloaded word list is picked up here, between rounds.
+					if cfg, changed := g.ga.updateConfig(g.Name, g.config); changed {
+						log.Println("Game", g.Name, "now using word list version", cfg.DictVersion)
+						g.config = cfg
+						for c := range g.clients {
+							c.cache = newScoreCache()
+						}
+					}
+					for c := range g.clients {
+						c.board, c.score = nil, nil
+						c.hints, c.repairs = 0, 0
+					}
+					g.tiles = g.config.Language.newTiles()
 					g.lastScores = make(map[*Client]*Score)
+					g.finishTimes = make(map[*Client]time.Duration)
 					g.resetClientReply()
 					g.state = StateWaitingRoundReady
 					g.sendToAllClientsExcept(cm.C, msg.RoundReady, nil)
@@ -127,6 +190,8 @@ func (g *Game) Run() {
 				g.clients[cm.C] = true
 				if g.allClientsTrue() {
 					g.state = StateRunning
+					g.roundStart = time.Now()
+					g.sendGameInfo()
 					tiles := g.tiles[:g.startingTileCnt]
 					log.Println("Sent tiles:", tiles)
 					g.sendToAllClients(msg.Start, tiles)
@@ -145,20 +210,14 @@ func (g *Game) Run() {
 					cm.C.sendSocketMsg(msg.Error, "Error: no active game!")
 				} else {
 					board := cm.Data.([]byte)
-					score := cm.C.ScoreMarshalledBoard(board)
+					score, err := cm.C.ScoreMarshalledBoard(board)
+					if err != nil {
+						cm.C.sendSocketMsg(msg.Error, "Error: "+err.Error())
+						continue
+					}
 					cm.C.SendScore(score)
 					if score.Win {
-						g.lastScores[cm.C] = score
-						g.resetClientReply()
-						g.state = StateWaitingScores
-						g.clients[cm.C] = true
-						g.sendToAllClientsExcept(cm.C, msg.SendBoard, nil)
-						if g.allClientsTrue() {
-							//TODO: get scores to determine a winner
-							g.sendToAllClients(msg.Result, nil)
-							g.state = StateOver
-							log.Println("Game is over!")
-						}
+						g.finish(cm.C, score)
 					}
 				}
 			case msg.SendBoard:
@@ -167,26 +226,89 @@ func (g *Game) Run() {
 					continue
 				}
 				board := cm.Data.([]byte)
-				score := cm.C.ScoreMarshalledBoard(board)
+				score, err := cm.C.ScoreMarshalledBoard(board)
+				if err != nil {
+					// Score an empty board so that the round can still end.
+					cm.C.sendSocketMsg(msg.Error, "Error: "+err.Error())
+					score = TileSet{}.scoreTiles(g.tiles[:cm.C.servedCnt], g.config, nil)
+				}
 				cm.C.SendScore(score)
 				g.clients[cm.C] = true
 				g.lastScores[cm.C] = score
 				// TODO: add timeout
 				if g.allClientsTrue() {
-					//TODO: get scores to determine a winner
-					g.sendToAllClients(msg.Result, nil)
-					g.state = StateOver
-					log.Println("Game is over!")
+					g.endRound()
+				}
+			case msg.Define:
+				var word string
+				if err := json.Unmarshal(cm.Data.([]byte), &word); err != nil {
+					cm.C.sendSocketMsg(msg.Error, "Error: "+err.Error())
+					continue
+				}
+				def, ok := g.config.Defs.Define(word)
+				cm.C.sendSocketMsg(msg.Define, msg.DefinitionData{Word: word, Definition: def, Found: ok})
+			case msg.Anagrams:
+				var req msg.AnagramRequest
+				if err := json.Unmarshal(cm.Data.([]byte), &req); err != nil {
+					cm.C.sendSocketMsg(msg.Error, "Error: "+err.Error())
+					continue
+				}
+				result, err := FindAnagrams(g.config.Dict, g.config.Language, req)
+				if err != nil {
+					cm.C.sendSocketMsg(msg.Error, "Error: "+err.Error())
+					continue
+				}
+				cm.C.sendSocketMsg(msg.Anagrams, result)
+			case msg.Challenge:
+				var word string
+				if err := json.Unmarshal(cm.Data.([]byte), &word); err != nil {
+					cm.C.sendSocketMsg(msg.Error, "Error: "+err.Error())
+					continue
+				}
+				g.challenge(cm.C, word)
+			case msg.Vote:
+				var b msg.BallotData
+				if err := json.Unmarshal(cm.Data.([]byte), &b); err != nil {
+					cm.C.sendSocketMsg(msg.Error, "Error: "+err.Error())
+					continue
+				}
+				g.castBallot(cm.C, b)
+			case msg.AddBot:
+				var level string
+				if err := json.Unmarshal(cm.Data.([]byte), &level); err != nil {
+					cm.C.sendSocketMsg(msg.Error, "Error: "+err.Error())
+					continue
+				}
+				g.addBot(cm.C, level)
+			case msg.RemoveBot:
+				g.removeBot(cm.C)
+			case msg.Hint:
+				if g.state != StateRunning {
+					cm.C.sendSocketMsg(msg.Error, "Error: no active game!")
+				} else {
+					g.hint(cm.C, cm.Data.([]byte))
+				}
+			case msg.Repair:
+				if g.state != StateRunning {
+					cm.C.sendSocketMsg(msg.Error, "Error: no active game!")
+				} else {
+					g.repair(cm.C, cm.Data.([]byte))
 				}
 			case msg.Exit:
-				cm.C.Close()
+				// Client.Close would send Exit back to this goroutine.
+				cm.C.conn.Close()
 				delete(g.clients, cm.C)
+				g.dropVoter(cm.C)
 				log.Println("runGame: Removing client from game")
 				if len(g.clients) == 0 {
 					g.Close()
 					log.Println("No more clients - closing game")
 				}
 			}
+		case r := <-g.analyses:
+			g.sendAnalysis(r)
+		case r := <-g.hints:
+			g.sendHint(r)
 		case <-g.quit:
 			return
 		}
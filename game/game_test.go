@@ -1,6 +1,11 @@
 package game
 
 import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -56,7 +61,7 @@ func NewFakeWebsocketConn(t *testing.T) *FakeWebsocketConn {
 }
 
 func TestExitMessage(t *testing.T) {
-	ga := NewGameAssigner()
+	ga := NewGameAssigner(map[string]Dictionary{"test": testDict}, nil, "test", nil)
 	go ga.Run()
 	if len(ga.games) != 0 {
 		t.Errorf("Game count pre-client: Got %v; Expected 0", len(ga.games))
@@ -74,7 +79,7 @@ func TestExitMessage(t *testing.T) {
 }
 
 func TestSynchonousStart(t *testing.T) {
-	ga := NewGameAssigner()
+	ga := NewGameAssigner(map[string]Dictionary{"test": testDict}, nil, "test", nil)
 	connA := NewFakeWebsocketConn(t)
 	ga.StartNewClient(connA)
 	connB := NewFakeWebsocketConn(t)
@@ -84,3 +89,635 @@ func TestSynchonousStart(t *testing.T) {
 	connA.sendMsg(msg.Start, nil)
 	connB.sendMsg(msg.Start, nil)
 }
+
+func TestWordListChoice(t *testing.T) {
+	dicts := map[string]Dictionary{
+		"uk": Dict{"COLOUR": {}},
+		"us": Dict{"COLOR": {}},
+	}
+	ga := NewGameAssigner(dicts, nil, "uk", nil)
+	color := makeTestBoard(5, 1, "C", "O", "L", "O", "R")
+	tiles := makeTestTiles("C", "O", "L", "O", "R")
+	tests := []struct {
+		asked, game, list string
+		win               bool
+	}{
+		{"", "global", "uk", false},
+		{"uk", "global", "uk", false},
+		{"us", "global-us", "us", true},
+		{"klingon", "global", "uk", false},
+	}
+	for _, input := range tests {
+		name, cfg, _ := ga.configFor(MsgGameRequest{WordList: input.asked})
+		if name != input.game {
+			testError(t, name, input.game, input.asked+" - game name")
+		}
+		s := color.scoreBoard(tiles, cfg, nil)
+		if s.WordList != input.list {
+			testError(t, s.WordList, input.list, input.asked+" - word list")
+		}
+		if s.Win != input.win {
+			testError(t, s.Win, input.win, input.asked+" - win")
+		}
+	}
+
+	dicts["spanish"] = Dict{"CHICO": {}}
+	if err := ga.ReloadDictionaries(dicts, nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, input := range []struct {
+		lang, game, list string
+	}{
+		{"spanish", "global-spanish", "spanish"},
+		{"english", "global", "uk"},
+		{"klingon", "global", "uk"},
+	} {
+		name, cfg, err := ga.configFor(MsgGameRequest{Language: input.lang})
+		if err != nil {
+			t.Errorf("%v: %v", input.lang, err)
+		}
+		if name != input.game || cfg.WordList != input.list {
+			testError(t, name+" "+cfg.WordList, input.game+" "+input.list, input.lang+" - game")
+		}
+		if name == "global-spanish" && (cfg.Language != LanguagePacks["spanish"] || cfg.MaxBoardTiles != 98) {
+			testError(t, cfg.MaxBoardTiles, 98, input.lang+" - bag size")
+		}
+	}
+	if _, _, err := ga.configFor(MsgGameRequest{Language: "german"}); err == nil {
+		t.Error("Expected an error for a language without a word list")
+	}
+
+	c := &Client{}
+	b, _ := json.Marshal(msg.JoinGameData{Name: "ann", Protocol: msg.ProtocolSparse, WordList: "us"})
+	if req := c.readJoinGame(b); req.WordList != "us" || req.C != c {
+		testError(t, req.WordList, "us", "word list asked for on joining")
+	}
+	if req := c.readJoinGame([]byte(`"bob"`)); req.WordList != "" || c.Name != "bob" {
+		testError(t, req.WordList, "", "word list asked for by an older player")
+	}
+}
+
+func TestGameChoices(t *testing.T) {
+	ga := NewGameAssigner(map[string]Dictionary{"test": testDict}, nil, "test", nil)
+	for _, input := range []struct {
+		req    MsgGameRequest
+		game   string
+		rule   string
+		layout string
+	}{
+		{MsgGameRequest{}, "global", "leftover", ""},
+		{MsgGameRequest{Rule: "leftover"}, "global", "leftover", ""},
+		{MsgGameRequest{Rule: "tile-count"}, "global-tile-count", "tile-count", ""},
+		{MsgGameRequest{Rule: "golf"}, "global", "leftover", ""},
+		{MsgGameRequest{Rule: "words", Layout: "classic"}, "global-words-classic", "words", "classic"},
+		{MsgGameRequest{Rule: "words", Layout: "none"}, "global-words", "words", ""},
+		{MsgGameRequest{Rule: "words", Layout: "checkers"}, "global-words", "words", ""},
+		{MsgGameRequest{Layout: "classic"}, "global", "leftover", ""},
+		{MsgGameRequest{Words: "hard"}, "global-hard", "leftover", ""},
+		{MsgGameRequest{Words: "normal"}, "global", "leftover", ""},
+		{MsgGameRequest{Words: "easy"}, "global", "leftover", ""},
+	} {
+		name, cfg, _ := ga.configFor(input.req)
+		if name != input.game || cfg.Rule.Name() != input.rule || cfg.LayoutName != input.layout {
+			testError(t, name+" "+cfg.Rule.Name()+" "+cfg.LayoutName, input.game+" "+input.rule+" "+input.layout, fmt.Sprintf("%+v", input.req))
+		}
+		if !cmp(cfg.Layout, Layouts[input.layout]) {
+			testError(t, cfg.Layout, Layouts[input.layout], fmt.Sprintf("%+v - layout", input.req))
+		}
+		if hard := input.game == "global-hard"; hard != (cfg.Words.MinLength == 3) {
+			testError(t, cfg.Words, WordRulePresets[input.req.Words], fmt.Sprintf("%+v - word rules", input.req))
+		}
+	}
+
+	for _, input := range []struct {
+		req     MsgGameRequest
+		game    string
+		penalty int
+	}{
+		{MsgGameRequest{Islands: true}, "global-islands", 0},
+		{MsgGameRequest{Islands: true, IslandPenalty: 3}, "global-islands-3", 3},
+		{MsgGameRequest{Islands: true, IslandPenalty: -3}, "global-islands", 0},
+		{MsgGameRequest{IslandPenalty: 3}, "global", 0},
+	} {
+		name, cfg, _ := ga.configFor(input.req)
+		if name != input.game || cfg.ScoreAllIslands != input.req.Islands || cfg.IslandPenalty != input.penalty {
+			testError(t, fmt.Sprintf("%v %v %v", name, cfg.ScoreAllIslands, cfg.IslandPenalty), input.game, fmt.Sprintf("%+v", input.req))
+		}
+	}
+
+	c := &Client{}
+	b, _ := json.Marshal(msg.JoinGameData{Name: "ann", Rule: "tile-count"})
+	if req := c.readJoinGame(b); req.Rule != "tile-count" {
+		testError(t, req.Rule, "tile-count", "rule asked for on joining")
+	}
+}
+
+func TestComputeAwards(t *testing.T) {
+	cat := makeTestBoard(3, 3, "C", "A", "T", "", "C", "", "", "T", "")
+	qi := makeTestBoard(2, 1, "Q", "I")
+	// AÑO has as many letters as CAT, though Ñ takes more than one byte.
+	ano := makeTestBoard(3, 1, "A", "Ñ", "O")
+	spanish := Config{Rule: leftoverRule{}, Dict: Dict{"AÑO": {}}, Language: LanguagePacks["spanish"]}
+	scores := []*Score{
+		cat.scoreBoard(makeTestTiles("C", "A", "T", "C", "T"), DefaultConfig(testDict), nil),
+		qi.scoreBoard(makeTestTiles("Q", "I"), DefaultConfig(testDict), nil),
+		nil,
+		ano.scoreBoard(makeTestTiles("A", "Ñ", "O"), spanish, nil),
+	}
+	awards := computeAwards([]string{"ann", "bob", "cy", "di"}, scores,
+		[]time.Duration{0, 90 * time.Second, 0, 0}, nil)
+
+	expected := map[string][]string{
+		"Longest word":           {"ann", "di"},
+		"Highest scoring word":   {"bob"},
+		"Most tiles in one word": {"ann", "di"},
+		"Most compact grid":      {"bob"},
+		"Fastest finish":         {"bob"},
+	}
+	if len(awards) != len(expected) {
+		testError(t, len(awards), len(expected), "award count")
+	}
+	for _, a := range awards {
+		if !cmp(a.Players, expected[a.Name]) {
+			testError(t, a.Players, expected[a.Name], a.Name)
+		}
+	}
+}
+
+func TestDefinitions(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "words.defs")
+	data := "# Comment\nqi\tthe vital force in Chinese thought\n\nCAT\ta small domesticated feline\n" +
+		"ACT\t" + strings.Repeat("to make lace by hand ", 10) + "\nNODEF\n"
+	if err := ioutil.WriteFile(filename, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defs, err := LoadDefinitions(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(defs) != 3 {
+		testError(t, len(defs), 3, "definition count")
+	}
+	if def, ok := defs.Define("qi"); !ok || def != "the vital force in Chinese thought" {
+		testError(t, def, "the vital force in Chinese thought", "definition of qi")
+	}
+	if _, ok := Definitions(nil).Define("QI"); ok {
+		t.Errorf("nil definitions defined QI")
+	}
+	if got := DefinitionsFile("lists/words.txt"); got != "lists/words.defs" {
+		testError(t, got, "lists/words.defs", "definitions file")
+	}
+
+	// Definitions of the words played are sent with the results, cut short if long.
+	cfg := DefaultConfig(testDict)
+	cfg.Defs = defs
+	ann, bob := &Client{Name: "ann"}, &Client{Name: "bob"}
+	g := &Game{
+		clients: map[*Client]bool{ann: true, bob: true},
+		lastScores: map[*Client]*Score{
+			ann: makeTestBoard(3, 1, "C", "A", "T").scoreBoard(makeTestTiles("C", "A", "T"), cfg, nil),
+			bob: makeTestBoard(1, 3, "A", "C", "T").scoreBoard(makeTestTiles("A", "C", "T"), cfg, nil),
+		},
+		config: cfg,
+	}
+	result := g.results()
+	if len(result.Definitions) != 2 {
+		testError(t, result.Definitions, "CAT and ACT", "definitions of words played")
+	}
+	if def := result.Definitions["CAT"]; def != "a small domesticated feline" {
+		testError(t, def, "a small domesticated feline", "definition of CAT")
+	}
+	if def := []rune(result.Definitions["ACT"]); len(def) > shortDefinitionLen || def[len(def)-1] != '…' {
+		testError(t, string(def), "a shortened definition", "definition of ACT")
+	}
+}
+
+func TestDictionaryReload(t *testing.T) {
+	ga := NewGameAssigner(map[string]Dictionary{"test": testDict}, nil, "test", nil)
+	name, cfg, _ := ga.configFor(MsgGameRequest{})
+	if cfg.DictVersion != 1 {
+		testError(t, cfg.DictVersion, 1, "first version")
+	}
+
+	// The default list may not go missing.
+	if err := ga.ReloadDictionaries(map[string]Dictionary{"other": testDict}, nil); err == nil {
+		t.Errorf("reloaded without the default word list")
+	}
+
+	reloaded := Dict{"ZO": {}}
+	defs := map[string]Definitions{"test": {"ZO": "a yak hybrid"}}
+	if err := ga.ReloadDictionaries(map[string]Dictionary{"test": reloaded}, defs); err != nil {
+		t.Fatal(err)
+	}
+	if _, fresh, _ := ga.configFor(MsgGameRequest{}); fresh.DictVersion != 2 || !fresh.Dict.Contains("ZO") {
+		testError(t, fresh.DictVersion, 2, "version of new games")
+	}
+	if def, _ := ga.Definitions("").Define("ZO"); def != "a yak hybrid" {
+		testError(t, def, "a yak hybrid", "reloaded definition")
+	}
+
+	// The running game keeps its word list until it asks for the latest one between rounds.
+	board := makeTestBoard(3, 1, "C", "A", "T")
+	if s := board.scoreBoard(makeTestTiles("C", "A", "T"), cfg, nil); s.DictVersion != 1 || len(s.Words) != 1 {
+		testError(t, s.DictVersion, 1, "version scored by running game")
+	}
+	cfg, changed := ga.updateConfig(name, cfg)
+	if !changed || cfg.DictVersion != 2 {
+		testError(t, cfg.DictVersion, 2, "version of next round")
+	}
+	if s := board.scoreBoard(makeTestTiles("C", "A", "T"), cfg, nil); s.DictVersion != 2 || len(s.Words) != 0 {
+		testError(t, s.DictVersion, 2, "version scored in next round")
+	}
+	if _, changed := ga.updateConfig(name, cfg); changed {
+		t.Errorf("updated a game already on the latest version")
+	}
+}
+
+func TestGameDictionary(t *testing.T) {
+	house, _ := LoadHouseRules("")
+	house.Add("global", BanList, "CAT")
+	ga := NewGameAssigner(map[string]Dictionary{"test": testDict}, nil, "test", house)
+	if d := ga.GameDictionary("global", "test"); d == nil || d.Contains("CAT") || !d.Contains("ACT") {
+		t.Error("Expected the game's house rules over its word list")
+	}
+	if d := ga.GameDictionary("global-words", ""); d == nil || !d.Contains("CAT") {
+		t.Error("Expected other games to keep their own house rules")
+	}
+	if d := ga.GameDictionary("global", "missing"); d != nil {
+		testError(t, d, nil, "dictionary of a missing word list")
+	}
+}
+
+func TestWordDispute(t *testing.T) {
+	house, _ := LoadHouseRules("")
+	ga := NewGameAssigner(map[string]Dictionary{"test": testDict}, nil, "test", house)
+	name, cfg, _ := ga.configFor(MsgGameRequest{})
+	g := &Game{
+		Name:        name,
+		tiles:       makeTestTiles("C", "A", "X"),
+		clients:     make(map[*Client]bool),
+		lastScores:  make(map[*Client]*Score),
+		finishTimes: make(map[*Client]time.Duration),
+		config:      cfg,
+		ga:          ga,
+		state:       StateRunning,
+	}
+	conns := make(map[string]*FakeWebsocketConn)
+	players := make(map[string]*Client)
+	for _, n := range []string{"ann", "bob", "cy"} {
+		conns[n] = &FakeWebsocketConn{chRead: make(chan []byte, 10), t: t}
+		players[n] = &Client{Name: n, conn: conns[n], game: g, cache: newScoreCache(), servedCnt: 3}
+		g.clients[players[n]] = false
+	}
+	ann := players["ann"]
+	ann.board = makeTestBoard(3, 1, "C", "A", "X").setOfAllTiles()
+	ann.score = ann.board.scoreTiles(g.tiles, g.config, ann.cache)
+
+	// Only words on the challenger's board which are missing from the dictionary count.
+	g.challenge(ann, "CAT")
+	conns["ann"].waitForMsg(msg.Error)
+	g.challenge(players["bob"], "CAX")
+	conns["bob"].waitForMsg(msg.Error)
+
+	// A tie rejects the word.
+	g.challenge(ann, "CAX")
+	for _, n := range []string{"bob", "cy"} {
+		var v msg.VoteData
+		json.Unmarshal(conns[n].waitForMsg(msg.Vote), &v)
+		if v.Word != "CAX" || v.Challenger != "ann" {
+			testError(t, v, "a vote on CAX by ann", "vote sent to "+n)
+		}
+	}
+	g.challenge(ann, "CAX")
+	conns["ann"].waitForMsg(msg.Error)
+	g.castBallot(ann, msg.BallotData{Word: "CAX", Accept: true})
+	conns["ann"].waitForMsg(msg.Error)
+	g.castBallot(players["bob"], msg.BallotData{Word: "CAX", Accept: true})
+	g.castBallot(players["cy"], msg.BallotData{Word: "CAX", Accept: false})
+	var result msg.VoteResultData
+	json.Unmarshal(conns["ann"].waitForMsg(msg.VoteResult), &result)
+	if result.Accepted || result.For != 1 || result.Against != 1 {
+		testError(t, result, "rejected 1 to 1", "tied vote")
+	}
+	conns["bob"].waitForMsg(msg.VoteResult)
+	conns["cy"].waitForMsg(msg.VoteResult)
+
+	// A majority accepts the word, which rescores the challenger's board.
+	g.challenge(ann, "CAX")
+	conns["bob"].waitForMsg(msg.Vote)
+	conns["cy"].waitForMsg(msg.Vote)
+	g.castBallot(players["cy"], msg.BallotData{Word: "CAX", Accept: true})
+	g.castBallot(players["bob"], msg.BallotData{Word: "CAX", Accept: true})
+	json.Unmarshal(conns["ann"].waitForMsg(msg.VoteResult), &result)
+	if !result.Accepted || result.For != 2 {
+		testError(t, result, "accepted 2 to 0", "majority vote")
+	}
+	var score struct{ Win bool }
+	json.Unmarshal(conns["ann"].waitForMsg(msg.Score), &score)
+	if !score.Win || !ann.score.Win {
+		testError(t, score.Win, true, "rescored board")
+	}
+	// A board which now wins finishes the round, as if it had been verified.
+	if g.state != StateWaitingScores || g.lastScores[ann] != ann.score {
+		testError(t, g.state, StateWaitingScores, "state after a winning rescore")
+	}
+	for _, n := range []string{"bob", "cy"} {
+		conns[n].waitForMsg(msg.VoteResult)
+		conns[n].waitForMsg(msg.SendBoard)
+	}
+	if lists := house.Lists()[name]; !cmp(lists.Candidates, []string{"CAX"}) || len(lists.Allow) != 0 {
+		testError(t, lists, "CAX as a candidate only", "house rules after vote")
+	}
+
+	// The word now counts for the rest of the game, even with a reloaded dictionary.
+	if err := ga.ReloadDictionaries(map[string]Dictionary{"test": testDict}, nil); err != nil {
+		t.Fatal(err)
+	}
+	g.config, _ = ga.updateConfig(g.Name, g.config)
+	if s := makeTestBoard(3, 1, "C", "A", "X").scoreBoard(g.tiles, g.config, nil); !s.Win {
+		testError(t, s.Nonwords, "no non-words", "accepted word after reload")
+	}
+}
+
+func TestHints(t *testing.T) {
+	cfg := DefaultConfig(testDict)
+	served := makeTestTiles("C", "A", "T", "S", "I", "Q", "T")
+	place := func(tiles map[Vec]int) TileSet {
+		s := make(TileSet)
+		for v, id := range tiles {
+			tile := served[id]
+			s[v] = &tile
+		}
+		return s
+	}
+	for _, tc := range []struct {
+		name  string
+		board TileSet
+		word  string
+		tiles []msg.PlacedTile
+		moved *msg.PlacedTile
+	}{
+		{
+			name:  "empty board",
+			board: TileSet{},
+			word:  "QI",
+			tiles: []msg.PlacedTile{{X: 0, Y: 8, Value: "Q", ID: 5}, {X: 1, Y: 8, Value: "I", ID: 4}},
+		},
+		{
+			name:  "attach to a word",
+			board: place(map[Vec]int{{0, 0}: 0, {1, 0}: 1, {2, 0}: 2}),
+			word:  "AS",
+			tiles: []msg.PlacedTile{{X: 1, Y: 1, Value: "S", ID: 3}},
+		},
+		{
+			name:  "move an invalid tile",
+			board: place(map[Vec]int{{0, 0}: 0, {1, 0}: 1, {2, 0}: 2, {5, 5}: 3}),
+			word:  "AS",
+			tiles: []msg.PlacedTile{{X: 1, Y: 1, Value: "S", ID: 3}},
+			moved: &msg.PlacedTile{X: 5, Y: 5, Value: "S", ID: 3},
+		},
+	} {
+		h, ok := findHint(tc.board, served, cfg)
+		if !ok {
+			t.Errorf("%v: no hint found", tc.name)
+			continue
+		}
+		if h.Word != tc.word || !cmp(h.Tiles, tc.tiles) || !cmp(h.Moved, tc.moved) {
+			testError(t, h, msg.HintData{Word: tc.word, Tiles: tc.tiles, Moved: tc.moved}, tc.name)
+		}
+	}
+	if h, ok := findHint(place(map[Vec]int{{0, 0}: 5, {1, 0}: 4}), served[4:], cfg); ok {
+		testError(t, h, "no hint", "no tiles left")
+	}
+
+	// Boards sent as grids may carry any IDs, so tiles are matched to those served.
+	grid := TileSet{{0, 0}: &Tile{Value: "C", ID: 1}, {1, 0}: &Tile{Value: "A", ID: 1}, {2, 0}: &Tile{Value: "T", ID: 9}}
+	if got, err := matchServed(grid, served); err != nil || !cmp(got, place(map[Vec]int{{0, 0}: 0, {1, 0}: 1, {2, 0}: 2})) {
+		testError(t, got, "C, A and T as served", "matching a grid board")
+	}
+	grid[Vec{3, 0}] = &Tile{Value: "S", ID: 3}
+	grid[Vec{4, 0}] = &Tile{Value: "S", ID: 3}
+	if _, err := matchServed(grid, served); err == nil {
+		testError(t, err, "an error", "matching a second S")
+	}
+
+	// Hints are limited, and cost points at the end of the round.
+	ga := NewGameAssigner(map[string]Dictionary{"test": testDict}, nil, "test", nil)
+	_, cfg, _ = ga.configFor(MsgGameRequest{})
+	g := &Game{
+		tiles:      served,
+		clients:    make(map[*Client]bool),
+		lastScores: make(map[*Client]*Score),
+		config:     cfg,
+		ga:         ga,
+		state:      StateRunning,
+		hints:      make(chan hintResult),
+	}
+	conn := &FakeWebsocketConn{chRead: make(chan []byte, 10), t: t}
+	ann := &Client{Name: "ann", conn: conn, game: g, servedCnt: len(served), protocol: msg.ProtocolSparse}
+	g.clients[ann] = true
+	board, _ := json.Marshal([]msg.PlacedTile{{X: 0, Y: 0, Value: "C", ID: 0}, {X: 1, Y: 0, Value: "A", ID: 1}, {X: 2, Y: 0, Value: "T", ID: 2}})
+
+	g.hint(ann, board)
+	conn.waitForMsg(msg.Error)
+	g.config.Hints = HintRules{Allowed: true, Limit: 2, Cooldown: time.Hour, Cost: 5}
+	var h msg.HintData
+	// Hints are found away from the game goroutine, and only one at a time.
+	g.hint(ann, board)
+	g.hint(ann, board)
+	conn.waitForMsg(msg.Error)
+	g.sendHint(<-g.hints)
+	json.Unmarshal(conn.waitForMsg(msg.Hint), &h)
+	if h.Word != "AS" || h.Left != 1 {
+		testError(t, h, "AS with 1 hint left", "first hint")
+	}
+	g.hint(ann, board)
+	conn.waitForMsg(msg.Error)
+	ann.lastHint = time.Now().Add(-time.Hour)
+	g.hint(ann, board)
+	g.sendHint(<-g.hints)
+	json.Unmarshal(conn.waitForMsg(msg.Hint), &h)
+	if h.Left != 0 {
+		testError(t, h.Left, 0, "hints left after the last")
+	}
+	ann.lastHint = time.Now().Add(-time.Hour)
+	g.hint(ann, board)
+	conn.waitForMsg(msg.Error)
+
+	// Hints found once their round has ended are dropped.
+	g.config.Hints.Limit = 0
+	ann.lastHint = time.Now().Add(-time.Hour)
+	g.hint(ann, board)
+	g.round++
+	g.sendHint(<-g.hints)
+	if ann.hints != 2 || ann.hinting {
+		testError(t, ann.hints, 2, "hints after a late hint")
+	}
+
+	// Fewer leftover points are better, so hints add to them.
+	g.lastScores[ann] = &Score{Pts: 20}
+	if r := g.results(); r.Players[0].Pts != 30 || r.Players[0].Hints != 2 {
+		testError(t, r.Players[0], "30 points after 2 hints", "results")
+	}
+}
+
+func TestRepair(t *testing.T) {
+	cfg := DefaultConfig(testDict)
+	served := makeTestTiles("C", "A", "T", "S", "I", "Q", "T")
+	place := func(tiles map[Vec]int) TileSet {
+		s := make(TileSet)
+		for v, id := range tiles {
+			tile := served[id]
+			s[v] = &tile
+		}
+		return s
+	}
+	for _, tc := range []struct {
+		name  string
+		board TileSet
+		moves []msg.RepairMove
+	}{
+		{
+			name:  "valid board",
+			board: place(map[Vec]int{{0, 0}: 0, {1, 0}: 1, {2, 0}: 2}),
+		},
+		{
+			name:  "move an unconnected tile",
+			board: place(map[Vec]int{{0, 0}: 0, {1, 0}: 1, {2, 0}: 2, {5, 5}: 3}),
+			moves: []msg.RepairMove{{
+				From: msg.PlacedTile{X: 5, Y: 5, Value: "S", ID: 3},
+				To:   &msg.PlacedTile{X: 1, Y: 1, Value: "S", ID: 3},
+			}},
+		},
+		{
+			name:  "remove a tile with nowhere to go",
+			board: place(map[Vec]int{{0, 0}: 0, {1, 0}: 1, {2, 0}: 2, {3, 0}: 5}),
+			moves: []msg.RepairMove{{From: msg.PlacedTile{X: 3, Y: 0, Value: "Q", ID: 5}}},
+		},
+		{
+			name:  "keep the most tiles, not the most points",
+			board: place(map[Vec]int{{0, 0}: 5, {1, 0}: 4, {0, 5}: 0, {1, 5}: 1, {2, 5}: 2}),
+			moves: []msg.RepairMove{
+				{From: msg.PlacedTile{X: 0, Y: 0, Value: "Q", ID: 5}},
+				{From: msg.PlacedTile{X: 1, Y: 0, Value: "I", ID: 4}},
+			},
+		},
+		{
+			// S would first fit under the A, leaving T nowhere to go, but both fit when S
+			// makes CATS and T makes AT.
+			name:  "move as many tiles as fit",
+			board: place(map[Vec]int{{0, 0}: 0, {1, 0}: 1, {2, 0}: 2, {5, 5}: 3, {7, 7}: 6}),
+			moves: []msg.RepairMove{
+				{
+					From: msg.PlacedTile{X: 5, Y: 5, Value: "S", ID: 3},
+					To:   &msg.PlacedTile{X: 3, Y: 0, Value: "S", ID: 3},
+				},
+				{
+					From: msg.PlacedTile{X: 7, Y: 7, Value: "T", ID: 6},
+					To:   &msg.PlacedTile{X: 1, Y: 1, Value: "T", ID: 6},
+				},
+			},
+		},
+	} {
+		if moves := repairBoard(tc.board, cfg); !cmp(moves, tc.moves) {
+			testError(t, moves, tc.moves, tc.name)
+		}
+	}
+
+	// Repairs may be turned off, and are shown in the results.
+	ga := NewGameAssigner(map[string]Dictionary{"test": testDict}, nil, "test", nil)
+	_, cfg, _ = ga.configFor(MsgGameRequest{})
+	g := &Game{
+		tiles:      served,
+		clients:    make(map[*Client]bool),
+		lastScores: make(map[*Client]*Score),
+		config:     cfg,
+		ga:         ga,
+		state:      StateRunning,
+	}
+	conn := &FakeWebsocketConn{chRead: make(chan []byte, 10), t: t}
+	ann := &Client{Name: "ann", conn: conn, game: g, servedCnt: len(served), protocol: msg.ProtocolSparse}
+	g.clients[ann] = true
+	valid := []msg.PlacedTile{{X: 0, Y: 0, Value: "C", ID: 0}, {X: 1, Y: 0, Value: "A", ID: 1}, {X: 2, Y: 0, Value: "T", ID: 2}}
+	board, _ := json.Marshal(append(valid, msg.PlacedTile{X: 3, Y: 0, Value: "Q", ID: 5}))
+
+	g.repair(ann, board)
+	conn.waitForMsg(msg.Error)
+	g.config.Repairs = true
+	var r msg.RepairData
+	g.repair(ann, board)
+	json.Unmarshal(conn.waitForMsg(msg.Repair), &r)
+	if len(r.Moves) != 1 || r.Moves[0].To != nil {
+		testError(t, r, "Q sent back to the tray", "repair")
+	}
+	board, _ = json.Marshal(valid)
+	g.repair(ann, board)
+	conn.waitForMsg(msg.Error)
+	g.lastScores[ann] = &Score{Pts: 20}
+	if res := g.results(); res.Players[0].Repairs != 1 {
+		testError(t, res.Players[0].Repairs, 1, "repairs in results")
+	}
+}
+
+func TestAnalysis(t *testing.T) {
+	ga := NewGameAssigner(map[string]Dictionary{"test": testDict}, nil, "test", nil)
+	words := []msg.WordScore{{Word: "CATS", Pts: 6}, {Word: "ACT", Pts: 5}, {Word: "CAT", Pts: 5}, {Word: "AS", Pts: 2}, {Word: "AT", Pts: 2}, {Word: "IS", Pts: 2}}
+	ga.Analyze = func(hand []Tile, cfg Config) (Board, []msg.WordScore) {
+		return makeTestBoard(4, 1, "C", "A", "T", "S"), words
+	}
+	_, cfg, _ := ga.configFor(MsgGameRequest{})
+	g := &Game{
+		tiles:      makeTestTiles("C", "A", "T", "S"),
+		clients:    make(map[*Client]bool),
+		lastScores: make(map[*Client]*Score),
+		config:     cfg,
+		ga:         ga,
+		state:      StateWaitingScores,
+		analyses:   make(chan analysisResult),
+		quit:       make(chan struct{}),
+	}
+	defer close(g.quit)
+	conn := &FakeWebsocketConn{chRead: make(chan []byte, 10), t: t}
+	ann := &Client{Name: "ann", conn: conn, game: g, servedCnt: 4}
+	bot := &Client{Name: "bot", conn: &FakeWebsocketConn{chRead: make(chan []byte, 10), t: t}, game: g, servedCnt: 4, bot: true}
+	g.clients[ann] = true
+	g.clients[bot] = true
+	g.lastScores[ann] = makeTestBoard(3, 1, "C", "A", "T").scoreBoard(g.tiles[:3], cfg, nil)
+
+	g.endRound()
+	conn.waitForMsg(msg.Result)
+	r := <-g.analyses
+	if r.c != ann {
+		testError(t, r.c.Name, "ann", "player analysed")
+	}
+	select {
+	case r := <-g.analyses:
+		testError(t, r.c.Name, "no one", "bot analysed")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// Words on the player's board are not counted as missed.
+	g.sendAnalysis(r)
+	var a msg.AnalysisData
+	json.Unmarshal(conn.waitForMsg(msg.Analysis), &a)
+	missed := []msg.WordScore{{Word: "CATS", Pts: 6}, {Word: "ACT", Pts: 5}, {Word: "AS", Pts: 2}, {Word: "AT", Pts: 2}, {Word: "IS", Pts: 2}}
+	if len(a.Board) != 4 || a.Longest != "CATS" || !cmp(a.Missed, missed) {
+		testError(t, a, "a board of 4 tiles, CATS as the longest word and 5 missed words", "analysis")
+	}
+
+	// Analyses of earlier rounds are dropped.
+	g.round++
+	g.sendAnalysis(r)
+	select {
+	case m := <-conn.chRead:
+		testError(t, msg.Type(m[0]), "no message", "late analysis")
+	default:
+	}
+
+	// The longest word is the one of the most tiles, not the most bytes.
+	words = []msg.WordScore{{Word: "AÑO"}, {Word: "CHAL"}, {Word: "ALTO"}}
+	a = analysis(Board{}, words, nil, LanguagePacks["spanish"].multiLetterTiles())
+	if a.Longest != "ALTO" {
+		testError(t, a.Longest, "ALTO", "longest Spanish word")
+	}
+}
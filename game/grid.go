@@ -0,0 +1,113 @@
+package game
+
+// A grid indexes a TileSet by position within its bounding box, so that neighbouring tiles
+// can be found without map lookups.  Cells are stored row by row, which matches the order
+// of TileSet.sortedVecs.
+type grid struct {
+	min   Vec
+	size  Vec
+	cells []*Tile
+}
+
+// toGrid returns a grid holding the tiles of s.
+func (s TileSet) toGrid() *grid {
+	g := &grid{}
+	first := true
+	var max Vec
+	for v := range s {
+		if first || v.X < g.min.X {
+			g.min.X = v.X
+		}
+		if first || v.Y < g.min.Y {
+			g.min.Y = v.Y
+		}
+		if first || v.X > max.X {
+			max.X = v.X
+		}
+		if first || v.Y > max.Y {
+			max.Y = v.Y
+		}
+		first = false
+	}
+	if first {
+		return g
+	}
+	g.size = Vec{max.X - g.min.X + 1, max.Y - g.min.Y + 1}
+	g.cells = make([]*Tile, g.size.X*g.size.Y)
+	for v, t := range s {
+		g.cells[g.index(v)] = t
+	}
+	return g
+}
+
+// index returns the cell index of v, which must be within the grid.
+func (g *grid) index(v Vec) int {
+	return (v.Y-g.min.Y)*g.size.X + v.X - g.min.X
+}
+
+// vec returns the coordinates of the cell at index i.
+func (g *grid) vec(i int) Vec {
+	return Vec{i%g.size.X + g.min.X, i/g.size.X + g.min.Y}
+}
+
+// at returns the tile at v, or nil if there is none.
+func (g *grid) at(v Vec) *Tile {
+	if v.X < g.min.X || v.Y < g.min.Y || v.X >= g.min.X+g.size.X || v.Y >= g.min.Y+g.size.Y {
+		return nil
+	}
+	return g.cells[g.index(v)]
+}
+
+// find returns the root of i in a union-find forest, compressing the path as it goes.
+func find(parent []int, i int) int {
+	for parent[i] != i {
+		parent[i] = parent[parent[i]]
+		i = parent[i]
+	}
+	return i
+}
+
+// components returns the connected groups of tiles in this grid, ordered by their first
+// tile.
+func (g *grid) components() []TileSet {
+	parent := make([]int, len(g.cells))
+	for i := range parent {
+		parent[i] = i
+	}
+	union := func(a, b int) {
+		ra, rb := find(parent, a), find(parent, b)
+		if ra < rb {
+			parent[rb] = ra
+		} else if rb < ra {
+			parent[ra] = rb
+		}
+	}
+	for i, t := range g.cells {
+		if t == nil {
+			continue
+		}
+		if i%g.size.X+1 < g.size.X && g.cells[i+1] != nil {
+			union(i, i+1)
+		}
+		if i+g.size.X < len(g.cells) && g.cells[i+g.size.X] != nil {
+			union(i, i+g.size.X)
+		}
+	}
+
+	// The root of each component is its first tile in row order, since union always keeps
+	// the lower index.
+	var comps []TileSet
+	compOf := make([]int, len(g.cells))
+	for i, t := range g.cells {
+		if t == nil {
+			continue
+		}
+		root := find(parent, i)
+		if root == i {
+			compOf[i] = len(comps)
+			comps = append(comps, make(TileSet))
+		}
+		comps[compOf[root]][g.vec(i)] = t
+	}
+	return comps
+}
@@ -0,0 +1,396 @@
+package game
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/kathrelkeld/speed-scrabble/msg"
+)
+
+// HintRules decide whether players may ask for hints during a round, and what they cost.
+type HintRules struct {
+	// Allowed lets players ask for hints.
+	Allowed bool
+	// Limit is the most hints each player may take per round, or 0 for no limit.
+	Limit int
+	// Cooldown is how long a player must wait between hints.
+	Cooldown time.Duration
+	// Cost is taken off a player's final score for each hint taken, or added to it for
+	// rules where lower is better.
+	Cost int
+}
+
+// A hintResult is a hint for a player, found away from the game goroutine.
+type hintResult struct {
+	c     *Client
+	round int
+	data  msg.HintData
+	found bool
+}
+
+// hint starts looking for a word for the player to add to the board they sent, if the
+// game's rules allow them another hint.  As the search may take a while, it runs away from
+// the game goroutine, and the hint is sent from Run once it is found.
+func (g *Game) hint(c *Client, d []byte) {
+	rules := g.config.Hints
+	if !rules.Allowed {
+		c.sendSocketMsg(msg.Error, "Error: hints are not allowed in this game")
+		return
+	}
+	if c.hinting {
+		c.sendSocketMsg(msg.Error, "Error: a hint is already being found")
+		return
+	}
+	if rules.Limit > 0 && c.hints >= rules.Limit {
+		c.sendSocketMsg(msg.Error, fmt.Sprintf("Error: you have used all %v hints this round", rules.Limit))
+		return
+	}
+	if wait := rules.Cooldown - time.Since(c.lastHint); c.hints > 0 && wait > 0 {
+		c.sendSocketMsg(msg.Error, fmt.Sprintf("Error: next hint in %v", wait.Round(time.Second)))
+		return
+	}
+	served := g.tiles[:c.servedCnt]
+	board, err := parseBoard(d, c.protocol, served, g.config)
+	if err == nil {
+		board, err = matchServed(board, served)
+	}
+	if err != nil {
+		c.sendSocketMsg(msg.Error, "Error: "+err.Error())
+		return
+	}
+	c.hinting = true
+	go func(cfg Config, round int) {
+		h, ok := findHint(board, served, cfg)
+		select {
+		case g.hints <- hintResult{c, round, h, ok}:
+		case <-g.quit:
+		}
+	}(g.config.detached(), g.round)
+}
+
+// sendHint sends a hint to its player, and counts it against their limit, if they are still
+// in the game and the round it was asked for is still running.
+func (g *Game) sendHint(r hintResult) {
+	c := r.c
+	if _, ok := g.clients[c]; !ok {
+		return
+	}
+	c.hinting = false
+	if r.round != g.round || g.state != StateRunning {
+		log.Println("Dropping hint for", c.Name, "from round", r.round)
+		return
+	}
+	if !r.found {
+		c.sendSocketMsg(msg.Error, "Error: no hint found; try another tile")
+		return
+	}
+	c.hints++
+	c.lastHint = time.Now()
+	h := r.data
+	h.Left = -1
+	if rules := g.config.Hints; rules.Limit > 0 {
+		h.Left = rules.Limit - c.hints
+	}
+	log.Println("Game", g.Name, "gave", c.Name, "hint", h.Word)
+	c.sendSocketMsg(msg.Hint, h)
+}
+
+// hintPenalty returns the points to take off, or add to, a final score for the given
+// number of hints.
+func (cfg Config) hintPenalty(hints int) int {
+	penalty := cfg.Hints.Cost * hints
+	if cfg.Rule.LowerIsBetter() {
+		return -penalty
+	}
+	return penalty
+}
+
+// A hintWord is a dictionary word which can be made from the tiles a hint may use.
+type hintWord struct {
+	text   string
+	values []string // The value of each tile in the word.
+	pts    int
+}
+
+// A hintSearch looks for a word to add to a board.
+type hintSearch struct {
+	board   TileSet
+	anchors []Vec // Coordinates of the tiles the word may cross, in reading order.
+	pool    []Tile
+	must    *Tile // A tile the word must use, if any.
+	cfg     Config
+	wc      *wordChecker
+	weight  subsetWeight
+	cache   *scoreCache
+	valid   int // The number of valid tiles on the board.
+}
+
+// maxHintMoves is the most invalid tiles a hint tries to move, as each means searching for
+// words again.
+const maxHintMoves = 3
+
+// findHint returns a word which can be added to the board using tiles not yet on it,
+// crossing a single valid tile.  The tiles on the board must be served tiles, as returned
+// by matchServed, so that the rest can be told apart by ID.  Where the board has invalid tiles, it first tries to move
+// one of them into such a word.  An empty board gets any word which can be made.
+func findHint(board TileSet, served []Tile, cfg Config) (msg.HintData, bool) {
+	onBoard := make(map[int]bool)
+	for _, t := range board {
+		onBoard[t.ID] = true
+	}
+	var tray []Tile
+	for _, t := range served {
+		if !onBoard[t.ID] {
+			tray = append(tray, t)
+		}
+	}
+	wc := newWordChecker(cfg.Dict, cfg.Words, cfg.Language)
+	wc.voted = cfg.Accepted
+	weight := ruleWeight(cfg.Rule, cfg.Layout)
+	// The player's own cache is left alone, as it holds their last board.
+	cache := newScoreCache()
+
+	search := func(board TileSet, pool []Tile, must *Tile) (msg.HintData, bool) {
+		sc := board.extractScorable(wc, weight, cache, cfg.ScoreAllIslands)
+		s := &hintSearch{
+			board:   board,
+			anchors: sc.valid.sortedVecs(),
+			pool:    pool,
+			must:    must,
+			cfg:     cfg,
+			wc:      wc,
+			weight:  weight,
+			cache:   cache,
+			valid:   len(sc.valid),
+		}
+		return s.find()
+	}
+
+	valid := board.extractScorable(wc, weight, cache, cfg.ScoreAllIslands).valid
+	tried := 0
+	for _, v := range board.sortedVecs() {
+		if valid.contains(v) || tried == maxHintMoves {
+			continue
+		}
+		tried++
+		moved := board[v]
+		rest := board.copy()
+		delete(rest, v)
+		if h, ok := search(rest, append([]Tile{*moved}, tray...), moved); ok {
+			h.Moved = &msg.PlacedTile{X: v.X, Y: v.Y, Value: moved.Value, ID: moved.ID}
+			return h, true
+		}
+	}
+	return search(board, tray, nil)
+}
+
+// find returns the first word, from those using the most points, which can be laid across
+// one of the anchors, or anywhere on the board if there are none.
+func (s *hintSearch) find() (msg.HintData, bool) {
+	words := s.words()
+	if len(s.anchors) == 0 {
+		for _, w := range words {
+			if h, ok := s.tryAnywhere(w); ok {
+				return h, true
+			}
+		}
+		return msg.HintData{}, false
+	}
+	for _, a := range s.anchors {
+		for _, w := range words {
+			for k, v := range w.values {
+				if v != s.board[a].Value {
+					continue
+				}
+				for _, d := range []Vec{{1, 0}, {0, 1}} {
+					if h, ok := s.try(w, a.add(d.scale(-k)), d, k); ok {
+						return h, true
+					}
+				}
+			}
+		}
+	}
+	return msg.HintData{}, false
+}
+
+// words returns every word which can be made from the pool, along with a tile from an
+// anchor if there are any, ordered by the points of the tiles.
+func (s *hintSearch) words() []hintWord {
+	counts := make(map[string]int)
+	points := make(map[string]int)
+	var values []string
+	add := func(t Tile) {
+		if _, ok := points[t.Value]; !ok {
+			values = append(values, t.Value)
+		}
+		points[t.Value] = t.Points
+	}
+	for _, t := range s.pool {
+		counts[t.Value]++
+		add(t)
+	}
+	extra := make(map[string]bool)
+	for _, a := range s.anchors {
+		extra[s.board[a].Value] = true
+		add(*s.board[a])
+	}
+	// Longer values come first, so that words are spelt with multi-letter tiles where
+	// possible, as they must be.
+	sort.Slice(values, func(i, j int) bool {
+		if len(values[i]) != len(values[j]) {
+			return len(values[i]) > len(values[j])
+		}
+		return values[i] < values[j]
+	})
+
+	w := &hintWalk{
+		dict:   s.cfg.Dict,
+		must:   s.must,
+		values: values,
+		counts: counts,
+		points: points,
+		extra:  extra,
+		found:  make(map[string]bool),
+	}
+	w.walk("", nil, len(s.anchors) > 0)
+	sort.SliceStable(w.words, func(i, j int) bool {
+		if w.words[i].pts != w.words[j].pts {
+			return w.words[i].pts > w.words[j].pts
+		}
+		return w.words[i].text < w.words[j].text
+	})
+	return w.words
+}
+
+// hintWalk walks the dictionary by prefix from the tiles at hand, as anagramSearch does,
+// rather than trying every word in it.
+type hintWalk struct {
+	dict   Dictionary
+	must   *Tile
+	values []string
+	counts map[string]int
+	points map[string]int
+	extra  map[string]bool
+	found  map[string]bool
+	words  []hintWord
+}
+
+// walk extends prefix, spelt with the tile values in spelt, taking tiles from counts along
+// with one tile whose value is in extra if needExtra is set.
+func (w *hintWalk) walk(prefix string, spelt []string, needExtra bool) {
+	if !needExtra {
+		w.record(prefix, spelt)
+	}
+	for _, v := range w.values {
+		next := prefix + v
+		if !w.dict.HasPrefix(next) {
+			continue
+		}
+		if w.counts[v] > 0 {
+			w.counts[v]--
+			w.walk(next, append(spelt, v), needExtra)
+			w.counts[v]++
+		}
+		if needExtra && w.extra[v] {
+			w.walk(next, append(spelt, v), false)
+		}
+	}
+}
+
+// record adds text, spelt with the tile values in spelt, if it is a word.  Only the first
+// spelling reached counts, which uses multi-letter tiles where possible, as it must.
+func (w *hintWalk) record(text string, spelt []string) {
+	if len(spelt) < 2 || w.found[text] || !w.dict.Contains(text) {
+		return
+	}
+	w.found[text] = true
+	if w.must != nil && !containsValue(spelt, w.must.Value) {
+		return
+	}
+	pts := 0
+	for _, v := range spelt {
+		pts += w.points[v]
+	}
+	w.words = append(w.words, hintWord{text, append([]string(nil), spelt...), pts})
+}
+
+func containsValue(values []string, v string) bool {
+	for _, u := range values {
+		if u == v {
+			return true
+		}
+	}
+	return false
+}
+
+// tryAnywhere looks for a place on the board for w, which must not touch any tile on it,
+// scanning from the middle row down and then up.
+func (s *hintSearch) tryAnywhere(w hintWord) (msg.HintData, bool) {
+	size := s.cfg.BoardSize
+	mid := size.Y / 2
+	for i := 0; i < size.Y; i++ {
+		y := mid + i/2
+		if i%2 == 1 {
+			y = mid - i/2 - 1
+		}
+		for x := 0; x+len(w.values) <= size.X; x++ {
+			if h, ok := s.try(w, Vec{x, y}, Vec{1, 0}, -1); ok {
+				return h, true
+			}
+		}
+	}
+	return msg.HintData{}, false
+}
+
+// try returns a hint for laying w in direction d from start, with its kth tile on the
+// board already, if k is not -1.  The word must touch no other tile, and every tile of it
+// must be valid once laid.
+func (s *hintSearch) try(w hintWord, start, d Vec, k int) (msg.HintData, bool) {
+	p := Vec{d.Y, d.X}
+	if s.board.contains(start.add(d.scale(-1))) || s.board.contains(start.add(d.scale(len(w.values)))) {
+		return msg.HintData{}, false
+	}
+	// Tiles of each value left to place, with the tile which must be used first.
+	left := make(map[string][]Tile)
+	for _, t := range s.pool {
+		if s.must != nil && t.ID == s.must.ID {
+			left[t.Value] = append([]Tile{t}, left[t.Value]...)
+		} else {
+			left[t.Value] = append(left[t.Value], t)
+		}
+	}
+	board := s.board.copy()
+	h := msg.HintData{Word: w.text}
+	for i, value := range w.values {
+		v := start.add(d.scale(i))
+		if i == k {
+			continue
+		}
+		if !s.cfg.inBounds(v) || board.contains(v) || s.board.contains(v.add(p)) || s.board.contains(v.add(p.scale(-1))) {
+			return msg.HintData{}, false
+		}
+		if len(left[value]) == 0 {
+			return msg.HintData{}, false
+		}
+		t := left[value][0]
+		left[value] = left[value][1:]
+		board[v] = &t
+		h.Tiles = append(h.Tiles, msg.PlacedTile{X: v.X, Y: v.Y, Value: t.Value, ID: t.ID})
+	}
+	if s.must != nil && len(left[s.must.Value]) > 0 && left[s.must.Value][0].ID == s.must.ID {
+		return msg.HintData{}, false
+	}
+
+	sc := board.extractScorable(s.wc, s.weight, s.cache, s.cfg.ScoreAllIslands)
+	if len(sc.valid) < s.valid+len(h.Tiles) {
+		return msg.HintData{}, false
+	}
+	for _, t := range h.Tiles {
+		if !sc.valid.contains(Vec{t.X, t.Y}) {
+			return msg.HintData{}, false
+		}
+	}
+	return h, true
+}
@@ -0,0 +1,284 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// HouseRules are allow and ban lists layered over the dictionaries, either for every game or
+// for a single room, which is named after its game.  A banned word is never valid, even if
+// it is also allowed.  Changes are saved to a file as they are made.
+// HouseRules are safe to use from several goroutines.
+type HouseRules struct {
+	mu       sync.RWMutex
+	filename string                  // Where the lists are saved; empty to keep them in memory.
+	rooms    map[string]*WordOverlay // Lists by room, with "" applying to every room.
+	changes  int                     // Counts every change, so that stale scores can be spotted.
+}
+
+// A WordOverlay lists the words added to and removed from a dictionary, each in sorted order.
+type WordOverlay struct {
+	Allow []string
+	Ban   []string
+	// Candidates are words which players voted to accept, for an admin to consider allowing.
+	// They have no effect until then.
+	Candidates []string
+}
+
+// Lists which a word may be added to.
+const (
+	AllowList     = "allow"
+	BanList       = "ban"
+	CandidateList = "candidates"
+)
+
+// LoadHouseRules returns the house rules saved in the given file, or empty rules if it does
+// not exist yet.  Changes are saved back to the same file, unless it is empty.
+func LoadHouseRules(filename string) (*HouseRules, error) {
+	h := &HouseRules{filename: filename, rooms: make(map[string]*WordOverlay)}
+	if filename == "" {
+		return h, nil
+	}
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &h.rooms); err != nil {
+		return nil, fmt.Errorf("%v: %v", filename, err)
+	}
+	for room, o := range h.rooms {
+		if o == nil {
+			delete(h.rooms, room)
+			continue
+		}
+		o.Allow = normalizeWords(o.Allow)
+		o.Ban = normalizeWords(o.Ban)
+		o.Candidates = normalizeWords(o.Candidates)
+	}
+	return h, nil
+}
+
+// normalizeWords returns the given words in upper case, sorted and without repeats.
+func normalizeWords(words []string) []string {
+	seen := make(map[string]bool)
+	var result []string
+	for _, w := range words {
+		w = strings.ToUpper(strings.TrimSpace(w))
+		if w != "" && !seen[w] {
+			seen[w] = true
+			result = append(result, w)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// Lists returns a copy of every room's lists.
+func (h *HouseRules) Lists() map[string]WordOverlay {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	result := make(map[string]WordOverlay)
+	for room, o := range h.rooms {
+		result[room] = WordOverlay{
+			Allow:      append([]string(nil), o.Allow...),
+			Ban:        append([]string(nil), o.Ban...),
+			Candidates: append([]string(nil), o.Candidates...),
+		}
+	}
+	return result
+}
+
+// Add adds a word to the allow, ban or candidate list of a room, or of every room if room is empty,
+// and saves the lists.
+func (h *HouseRules) Add(room, list, word string) error {
+	return h.edit(room, list, word, true)
+}
+
+// Remove removes a word from the allow, ban or candidate list of a room, or of every room if room is
+// empty, and saves the lists.
+func (h *HouseRules) Remove(room, list, word string) error {
+	return h.edit(room, list, word, false)
+}
+
+func (h *HouseRules) edit(room, list, word string, add bool) error {
+	word = strings.ToUpper(strings.TrimSpace(word))
+	if word == "" {
+		return fmt.Errorf("no word given")
+	}
+	if list != AllowList && list != BanList && list != CandidateList {
+		return fmt.Errorf("no list named %q; expected %q, %q or %q", list, AllowList, BanList, CandidateList)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	o := h.rooms[room]
+	if o == nil {
+		o = &WordOverlay{}
+		h.rooms[room] = o
+	}
+	words := &o.Allow
+	switch list {
+	case BanList:
+		words = &o.Ban
+	case CandidateList:
+		words = &o.Candidates
+	}
+	i := sort.SearchStrings(*words, word)
+	found := i < len(*words) && (*words)[i] == word
+	switch {
+	case add && !found:
+		*words = append(*words, "")
+		copy((*words)[i+1:], (*words)[i:])
+		(*words)[i] = word
+	case !add && found:
+		*words = append((*words)[:i], (*words)[i+1:]...)
+	default:
+		return nil
+	}
+	if len(o.Allow) == 0 && len(o.Ban) == 0 && len(o.Candidates) == 0 {
+		delete(h.rooms, room)
+	}
+	h.changes++
+	return h.save()
+}
+
+// save writes every list to the file, replacing it all at once.  h.mu must be held.
+func (h *HouseRules) save() error {
+	if h.filename == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(h.rooms, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := h.filename + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, h.filename)
+}
+
+// version returns a number which changes whenever the lists do.
+func (h *HouseRules) version() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.changes
+}
+
+// inList returns whether w is in the given list of the room or of every room.
+// h.mu must be held.
+func (h *HouseRules) inList(room, w string, list func(o *WordOverlay) []string) bool {
+	for _, r := range []string{"", room} {
+		if o := h.rooms[r]; o != nil {
+			words := list(o)
+			i := sort.SearchStrings(words, w)
+			if i < len(words) && words[i] == w {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func overlayAllow(o *WordOverlay) []string { return o.Allow }
+func overlayBan(o *WordOverlay) []string   { return o.Ban }
+
+// Dictionary returns base with the lists for the given room layered over it.  The result
+// follows any later changes to the lists.  Nil HouseRules return base unchanged.
+func (h *HouseRules) Dictionary(room string, base Dictionary) Dictionary {
+	if h == nil {
+		return base
+	}
+	return &houseDict{base: base, house: h, room: room}
+}
+
+// A houseDict is a Dictionary with the house rules of a room layered over it.
+type houseDict struct {
+	base  Dictionary
+	house *HouseRules
+	room  string
+}
+
+// banned returns whether w is on a ban list.
+func (d *houseDict) banned(w string) bool {
+	d.house.mu.RLock()
+	defer d.house.mu.RUnlock()
+	return d.house.inList(d.room, w, overlayBan)
+}
+
+// Contains returns whether w is a word which is not banned.
+func (d *houseDict) Contains(w string) bool {
+	d.house.mu.RLock()
+	defer d.house.mu.RUnlock()
+	if d.house.inList(d.room, w, overlayBan) {
+		return false
+	}
+	return d.house.inList(d.room, w, overlayAllow) || d.base.Contains(w)
+}
+
+// HasPrefix returns whether any word starts with p.  Banned words are not taken into
+// account, so this may also be true when every word starting with p is banned.
+func (d *houseDict) HasPrefix(p string) bool {
+	if d.base.HasPrefix(p) {
+		return true
+	}
+	d.house.mu.RLock()
+	defer d.house.mu.RUnlock()
+	for _, r := range []string{"", d.room} {
+		if o := d.house.rooms[r]; o != nil {
+			i := sort.SearchStrings(o.Allow, p)
+			if i < len(o.Allow) && strings.HasPrefix(o.Allow[i], p) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Each calls f with every word which is not banned in sorted order, until f returns false.
+// The lists are read once at the start, so changes made while iterating are not seen.
+func (d *houseDict) Each(f func(w string) bool) {
+	d.house.mu.RLock()
+	ban := make(map[string]bool)
+	var allow []string
+	for _, r := range []string{"", d.room} {
+		if o := d.house.rooms[r]; o != nil {
+			for _, w := range o.Ban {
+				ban[w] = true
+			}
+			allow = append(allow, o.Allow...)
+		}
+	}
+	d.house.mu.RUnlock()
+	allow = normalizeWords(allow)
+
+	// Merge the allowed words into the base words, which are both sorted.
+	i := 0
+	stopped := false
+	emit := func(w string) bool {
+		if ban[w] {
+			return true
+		}
+		stopped = !f(w)
+		return !stopped
+	}
+	d.base.Each(func(w string) bool {
+		for ; i < len(allow) && allow[i] <= w; i++ {
+			if allow[i] != w && !emit(allow[i]) {
+				return false
+			}
+		}
+		return emit(w)
+	})
+	for ; !stopped && i < len(allow); i++ {
+		emit(allow[i])
+	}
+}
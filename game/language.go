@@ -0,0 +1,169 @@
+package game
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// A LanguagePack holds the tiles and the word list used to play in one language.
+// A tile may hold several characters, such as the Spanish CH, which then count as a single
+// letter: a word must use the CH tile rather than a C tile followed by an H tile.
+type LanguagePack struct {
+	Name string
+	// WordList names the word list which words are checked against.  Empty means the
+	// server's default list.
+	WordList string
+	// Tiles holds the number of each tile in a full bag.
+	Tiles map[string]int
+	// Points holds the value of each tile.
+	Points map[string]int
+}
+
+// LanguagePacks lists every available language by name.
+// Only the English word list is built in; the others must be added to the server, which
+// refuses games in those languages until then.
+var LanguagePacks = map[string]*LanguagePack{
+	"english": english,
+	"spanish": {
+		Name:     "spanish",
+		WordList: "spanish",
+		Tiles: map[string]int{
+			"A": 12, "B": 2, "C": 4, "CH": 1, "D": 5, "E": 12, "F": 1, "G": 2, "H": 2, "I": 6,
+			"J": 1, "L": 4, "LL": 1, "M": 2, "N": 5, "Ñ": 1, "O": 9, "P": 2, "Q": 1, "R": 5,
+			"RR": 1, "S": 6, "T": 4, "U": 5, "V": 1, "X": 1, "Y": 1, "Z": 1,
+		},
+		Points: map[string]int{
+			"A": 1, "B": 3, "C": 3, "CH": 5, "D": 2, "E": 1, "F": 4, "G": 2, "H": 4, "I": 1,
+			"J": 8, "L": 1, "LL": 8, "M": 3, "N": 1, "Ñ": 8, "O": 1, "P": 3, "Q": 5, "R": 1,
+			"RR": 8, "S": 1, "T": 1, "U": 1, "V": 4, "X": 8, "Y": 4, "Z": 10,
+		},
+	},
+	"dutch": {
+		Name:     "dutch",
+		WordList: "dutch",
+		Tiles: map[string]int{
+			"A": 6, "B": 2, "C": 2, "D": 5, "E": 18, "F": 2, "G": 3, "H": 2, "I": 4, "IJ": 2,
+			"J": 2, "K": 3, "L": 3, "M": 3, "N": 10, "O": 6, "P": 2, "Q": 1, "R": 5, "S": 5,
+			"T": 5, "U": 3, "V": 2, "W": 2, "X": 1, "Y": 1, "Z": 2,
+		},
+		Points: map[string]int{
+			"A": 1, "B": 3, "C": 5, "D": 2, "E": 1, "F": 4, "G": 3, "H": 4, "I": 1, "IJ": 4,
+			"J": 4, "K": 3, "L": 3, "M": 3, "N": 1, "O": 1, "P": 3, "Q": 10, "R": 2, "S": 2,
+			"T": 2, "U": 4, "V": 4, "W": 5, "X": 8, "Y": 8, "Z": 4,
+		},
+	},
+	"german": {
+		Name:     "german",
+		WordList: "german",
+		Tiles: map[string]int{
+			"A": 5, "Ä": 1, "B": 2, "C": 2, "D": 4, "E": 15, "F": 2, "G": 3, "H": 4, "I": 6,
+			"J": 1, "K": 2, "L": 3, "M": 4, "N": 9, "O": 3, "Ö": 1, "P": 1, "Q": 1, "R": 6,
+			"S": 7, "T": 6, "U": 6, "Ü": 1, "V": 1, "W": 1, "X": 1, "Y": 1, "Z": 1,
+		},
+		Points: map[string]int{
+			"A": 1, "Ä": 6, "B": 3, "C": 4, "D": 1, "E": 1, "F": 4, "G": 2, "H": 2, "I": 1,
+			"J": 6, "K": 4, "L": 2, "M": 3, "N": 1, "O": 2, "Ö": 8, "P": 4, "Q": 10, "R": 1,
+			"S": 1, "T": 1, "U": 1, "Ü": 6, "V": 6, "W": 3, "X": 8, "Y": 10, "Z": 3,
+		},
+	},
+}
+
+// english is the language used unless a game asks for another.
+var english = &LanguagePack{
+	Name:   "english",
+	Tiles:  freqMap,
+	Points: pointValues,
+}
+
+// orEnglish returns p, or the English pack if p is nil.
+func (p *LanguagePack) orEnglish() *LanguagePack {
+	if p == nil {
+		return english
+	}
+	return p
+}
+
+// bagSize returns the number of tiles in a full set, which no board can exceed.
+func (p *LanguagePack) bagSize() int {
+	n := 0
+	for _, cnt := range p.orEnglish().Tiles {
+		n += cnt
+	}
+	return n
+}
+
+// multiLetterTiles returns the tiles which hold more than one character, longest first.
+func (p *LanguagePack) multiLetterTiles() []string {
+	var result []string
+	for l := range p.orEnglish().Tiles {
+		if utf8.RuneCountInString(l) > 1 {
+			result = append(result, l)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if len(result[i]) != len(result[j]) {
+			return len(result[i]) > len(result[j])
+		}
+		return result[i] < result[j]
+	})
+	return result
+}
+
+// spell splits w into letters, using the longest of the given multi-letter tiles possible
+// at each point, as word lists do.  The tiles must be sorted longest first.
+func spell(w string, multi []string) []string {
+	var letters []string
+	for w != "" {
+		n := 0
+		for _, l := range multi {
+			if strings.HasPrefix(w, l) {
+				n = len(l)
+				break
+			}
+		}
+		if n == 0 {
+			_, n = utf8.DecodeRuneInString(w)
+		}
+		letters = append(letters, w[:n])
+		w = w[n:]
+	}
+	return letters
+}
+
+// misspelling returns the first of the given multi-letter tiles which the given tiles spell
+// out with separate tiles, if any.
+func misspelling(tiles, multi []string) (string, bool) {
+	if len(multi) == 0 {
+		return "", false
+	}
+	spelt := spell(strings.Join(tiles, ""), multi)
+	for i := range spelt {
+		if i >= len(tiles) || spelt[i] != tiles[i] {
+			return spelt[i], true
+		}
+	}
+	return "", false
+}
+
+// Misspelling returns the first multi-letter tile of this language which the given tile
+// values spell out with separate tiles, if any.  Words spelt so are never valid.
+func (p *LanguagePack) Misspelling(values []string) (string, bool) {
+	return misspelling(values, p.multiLetterTiles())
+}
+
+// letters returns the value of each tile of this word, in order.
+func (w Word) letters() []string {
+	d := Vec{1, 0}
+	if w.Start.X == w.End.X {
+		d = Vec{0, 1}
+	}
+	var result []string
+	for v := w.Start; ; v = v.add(d) {
+		result = append(result, w.tiles[v].Value)
+		if v == w.End {
+			break
+		}
+	}
+	return result
+}
@@ -0,0 +1,104 @@
+package game
+
+import (
+	"sort"
+
+	"github.com/kathrelkeld/speed-scrabble/msg"
+)
+
+// A Premium multiplies the value of the letter or word placed on its square.
+type Premium int
+
+const (
+	NoPremium Premium = iota
+	DoubleLetter
+	TripleLetter
+	DoubleWord
+	TripleWord
+)
+
+var premiumToString = map[Premium]string{
+	NoPremium:    "",
+	DoubleLetter: "DL",
+	TripleLetter: "TL",
+	DoubleWord:   "DW",
+	TripleWord:   "TW",
+}
+
+func (p Premium) String() string {
+	return premiumToString[p]
+}
+
+// A Layout places premium squares at fixed coordinates of a player's board, counted from
+// the top left corner.  A nil Layout has no premium squares.
+type Layout map[Vec]Premium
+
+// Layouts lists every available board layout by name.
+var Layouts = map[string]Layout{
+	"none":    nil,
+	"classic": classicLayout,
+	"sparse":  sparseLayout,
+}
+
+// classicLayout copies the top left quarter of a Scrabble board.
+var classicLayout = Layout{
+	{0, 0}: TripleWord, {3, 0}: DoubleLetter, {7, 0}: TripleWord,
+	{1, 1}: DoubleWord, {5, 1}: TripleLetter,
+	{2, 2}: DoubleWord, {6, 2}: DoubleLetter,
+	{0, 3}: DoubleLetter, {3, 3}: DoubleWord, {7, 3}: DoubleLetter,
+	{4, 4}: DoubleWord,
+	{1, 5}: TripleLetter, {5, 5}: TripleLetter,
+	{2, 6}: DoubleLetter, {6, 6}: DoubleLetter,
+	{0, 7}: TripleWord, {3, 7}: DoubleLetter, {7, 7}: DoubleWord,
+}
+
+// sparseLayout has a handful of premium squares for shorter games.
+var sparseLayout = Layout{
+	{2, 2}: DoubleWord,
+	{5, 1}: TripleLetter,
+	{1, 5}: TripleLetter,
+	{4, 4}: DoubleLetter,
+}
+
+// squares returns the premium squares in this layout in the form sent to players.
+func (l Layout) squares() []msg.PremiumSquare {
+	vs := []Vec{}
+	for v := range l {
+		vs = append(vs, v)
+	}
+	sort.Slice(vs, func(i, j int) bool { return vs[i].less(vs[j]) })
+	result := []msg.PremiumSquare{}
+	for _, v := range vs {
+		result = append(result, msg.PremiumSquare{X: v.X, Y: v.Y, Kind: l[v].String()})
+	}
+	return result
+}
+
+// pts returns the value of this word, applying any premium squares between its Start and
+// End.
+func (w Word) pts(l Layout) int {
+	d := Vec{1, 0}
+	if w.Start.X == w.End.X {
+		d = Vec{0, 1}
+	}
+	total := 0
+	mult := 1
+	for v := w.Start; ; v = v.add(d) {
+		p := w.tiles[v].Points
+		switch l[v] {
+		case DoubleLetter:
+			p *= 2
+		case TripleLetter:
+			p *= 3
+		case DoubleWord:
+			mult *= 2
+		case TripleWord:
+			mult *= 3
+		}
+		total += p
+		if v == w.End {
+			break
+		}
+	}
+	return total * mult
+}
@@ -1,7 +1,12 @@
 package game
 
 import (
+	"fmt"
 	"log"
+	"sync"
+	"time"
+
+	"github.com/kathrelkeld/speed-scrabble/msg"
 )
 
 // The GameAssigner manages game assignments.
@@ -13,30 +18,208 @@ type GameAssigner struct {
 	GameExitChan chan *Game
 	// Map of id -> running games.
 	games map[string]*Game
+	// Guards config, dicts, defs and version, which are replaced when the word lists are
+	// reloaded.
+	mu sync.RWMutex
+	// Settings used for new games.
+	config Config
+	// Loaded word lists by name, shared between games.
+	dicts map[string]Dictionary
+	// Definitions of the words in each word list, by the list's name, where known.
+	defs map[string]Definitions
+	// Counts the times the word lists have been loaded, starting at 1.
+	version int
+	// Allow and ban lists layered over the word lists, if any.
+	house *HouseRules
+	// StartBot, if set, starts a computer player of the named level, which joins a game by
+	// sending join over the returned connection.  Bots are built outside this package.
+	StartBot func(level string, join msg.JoinGameData) (WebsocketConn, error)
+	// Analyze, if set, returns a board which uses every tile of a hand, or nil if none was
+	// found, along with every word which can be made from the hand, best first.  Players are
+	// shown what they could have made after each round.
+	Analyze func(hand []Tile, cfg Config) (Board, []msg.WordScore)
 	// Used to cleanly exit server.
 	quit chan struct{}
 }
 
-// NewGameAssigner returns a new GameAssigner.
-func NewGameAssigner() *GameAssigner {
+// NewGameAssigner returns a new GameAssigner whose games check words against one of the given
+// dictionaries, which are shared between games, with the house rules, if any, over it.
+// Definitions, if any, are kept by the name of their word list.  Players who do not ask for
+// a particular word list get the one named defaultList.  Word lists held in a map are compiled
+// into a DAWG, here and on reload, so that prefix queries stay quick.
+func NewGameAssigner(dicts map[string]Dictionary, defs map[string]Definitions, defaultList string, house *HouseRules) *GameAssigner {
+	dicts = withPrefixes(dicts)
+	cfg := DefaultConfig(dicts[defaultList])
+	cfg.WordList = defaultList
+	cfg.Defs = defs[defaultList]
+	cfg.DictVersion = 1
 	return &GameAssigner{
 		NewGameChan:  make(chan MsgGameRequest),
 		GameExitChan: make(chan *Game),
 		games:        make(map[string]*Game),
+		config:       cfg,
+		dicts:        dicts,
+		defs:         defs,
+		version:      1,
+		house:        house,
 		quit:         make(chan struct{}),
 	}
 }
 
+// configFor returns the name of the shared game for the given request, along with its
+// settings, or an error if the server does not have the word list of the language asked
+// for.  Unknown word lists get the language's list, and unknown languages get the default
+// one.  Unknown scoring rules get
+// the default rule, and unknown presets of word rules get the normal ones.  Layouts are only
+// used by the "words" rule, so other rules, and unknown layouts, get none.  Games which count
+// every island are named with their penalty, if any.
+func (ga *GameAssigner) configFor(req MsgGameRequest) (string, Config, error) {
+	ga.mu.RLock()
+	defer ga.mu.RUnlock()
+	cfg := ga.config
+	name := "global"
+	if lang, ok := LanguagePacks[req.Language]; ok && lang != cfg.Language {
+		if ga.dicts[lang.WordList] == nil {
+			return "", Config{}, fmt.Errorf("this server has no word list for %v", lang.Name)
+		}
+		cfg.Language = lang
+		cfg.MaxBoardTiles = lang.bagSize()
+		cfg.WordList = lang.WordList
+		name += "-" + lang.Name
+	}
+	if _, ok := ga.dicts[req.WordList]; ok && req.WordList != cfg.WordList {
+		cfg.WordList = req.WordList
+		name += "-" + req.WordList
+	}
+	if rule, ok := ScoringRules[req.Rule]; ok && rule.Name() != cfg.Rule.Name() {
+		cfg.Rule = rule
+		name += "-" + rule.Name()
+	}
+	if _, words := cfg.Rule.(wordsRule); words {
+		if layout, ok := Layouts[req.Layout]; ok && layout != nil && req.Layout != cfg.LayoutName {
+			cfg.Layout = layout
+			cfg.LayoutName = req.Layout
+			name += "-" + req.Layout
+		}
+	}
+	if words, ok := WordRulePresets[req.Words]; ok && req.Words != "normal" && req.Words != cfg.WordsName {
+		cfg.Words = words
+		cfg.WordsName = req.Words
+		name += "-" + req.Words
+	}
+	if req.Islands {
+		cfg.ScoreAllIslands = true
+		cfg.IslandPenalty = 0
+		name += "-islands"
+		if req.IslandPenalty > 0 {
+			cfg.IslandPenalty = req.IslandPenalty
+			name += fmt.Sprintf("-%v", req.IslandPenalty)
+		}
+	}
+	cfg.Dict = ga.house.Dictionary(name, ga.dicts[cfg.WordList])
+	cfg.Defs = ga.defs[cfg.WordList]
+	return name, cfg, nil
+}
+
+// ReloadDictionaries replaces the word lists and definitions, which must still include the
+// default list.  New games use them at once, and running games from their next round on, so
+// that rounds in progress finish with the lists they started with.
+func (ga *GameAssigner) ReloadDictionaries(dicts map[string]Dictionary, defs map[string]Definitions) error {
+	ga.mu.Lock()
+	defer ga.mu.Unlock()
+	if dicts[ga.config.WordList] == nil {
+		return fmt.Errorf("no word list named %q", ga.config.WordList)
+	}
+	ga.dicts = withPrefixes(dicts)
+	ga.defs = defs
+	ga.version++
+	ga.config.Dict = ga.dicts[ga.config.WordList]
+	ga.config.Defs = defs[ga.config.WordList]
+	ga.config.DictVersion = ga.version
+	log.Println("Reloaded word lists; now on version", ga.version)
+	return nil
+}
+
+// updateConfig returns the settings of the named game with the latest version of its word
+// list and definitions, and whether they changed.  A word list which is no longer loaded is
+// kept as it was.
+func (ga *GameAssigner) updateConfig(name string, cfg Config) (Config, bool) {
+	ga.mu.RLock()
+	defer ga.mu.RUnlock()
+	d := ga.dicts[cfg.WordList]
+	if cfg.DictVersion == ga.version || d == nil {
+		return cfg, false
+	}
+	cfg.Dict = ga.house.Dictionary(name, d)
+	cfg.Defs = ga.defs[cfg.WordList]
+	cfg.DictVersion = ga.version
+	return cfg, true
+}
+
+// SetHintRules sets whether players may ask for hints, and what they cost, in games started
+// from now on.
+func (ga *GameAssigner) SetHintRules(h HintRules) {
+	ga.mu.Lock()
+	defer ga.mu.Unlock()
+	ga.config.Hints = h
+}
+
+// SetRepairs sets whether players may ask for their board to be repaired, in games started
+// from now on.  Competitive games may want to turn this off.
+func (ga *GameAssigner) SetRepairs(allowed bool) {
+	ga.mu.Lock()
+	defer ga.mu.Unlock()
+	ga.config.Repairs = allowed
+}
+
+// Dictionary returns the latest version of the named word list, or of the default list if
+// list is empty, or nil if there is no such list.
+func (ga *GameAssigner) Dictionary(list string) Dictionary {
+	ga.mu.RLock()
+	defer ga.mu.RUnlock()
+	if list == "" {
+		list = ga.config.WordList
+	}
+	return ga.dicts[list]
+}
+
+// GameDictionary returns the latest version of the named word list with the house rules of
+// the named game over it, as that game checks words, or nil if there is no such list.
+func (ga *GameAssigner) GameDictionary(gameName, list string) Dictionary {
+	d := ga.Dictionary(list)
+	if d == nil {
+		return nil
+	}
+	return ga.house.Dictionary(gameName, d)
+}
+
+// Definitions returns the latest definitions of the named word list, or of the default list
+// if list is empty.
+func (ga *GameAssigner) Definitions(list string) Definitions {
+	ga.mu.RLock()
+	defer ga.mu.RUnlock()
+	if list == "" {
+		list = ga.config.WordList
+	}
+	return ga.defs[list]
+}
+
 // Run accepts game requests from clients, and creates/destroys games.
 func (ga *GameAssigner) Run() {
 	for {
 		select {
 		case req := <-ga.NewGameChan:
-			if ga.games["global"] == nil {
-				ga.games["global"] = ga.StartNewGame("global")
+			name, cfg, err := ga.configFor(req)
+			if err != nil {
+				log.Println("GameAssigner refusing client:", err)
+				req.joined <- gameAssignment{err: err}
+				continue
+			}
+			if ga.games[name] == nil {
+				ga.games[name] = ga.StartNewGame(name, cfg)
 			}
-			log.Println("GameAssigner assigning client to game")
-			ga.games["global"].AddPlayer(req.C)
+			log.Println("GameAssigner assigning client to game", name)
+			req.joined <- gameAssignment{game: ga.games[name]}
 		case game := <-ga.GameExitChan:
 			delete(ga.games, game.Name)
 		case <-ga.quit:
@@ -63,14 +246,18 @@ func (ga *GameAssigner) StartNewClient(conn WebsocketConn) *Client {
 	return c
 }
 
-// StartNewGame is used by the GameAssigner to make a new game.
-func (ga *GameAssigner) StartNewGame(name string) *Game {
+// StartNewGame is used by the GameAssigner to make a new game with the given settings.
+func (ga *GameAssigner) StartNewGame(name string, cfg Config) *Game {
 	game := &Game{
 		Name:            name,
-		tiles:           newTiles(),
+		tiles:           cfg.Language.newTiles(),
 		clients:         make(map[*Client]bool),
 		lastScores:      make(map[*Client]*Score),
+		finishTimes:     make(map[*Client]time.Duration),
+		config:          cfg,
 		toGameChan:      make(chan MsgFromClient),
+		analyses:        make(chan analysisResult),
+		hints:           make(chan hintResult),
 		startingTileCnt: 12,
 		ga:              ga,
 		quit:            make(chan struct{}),
@@ -82,7 +269,23 @@ func (ga *GameAssigner) StartNewGame(name string) *Game {
 // A MsgGameRequest is sent from a Client to ask to create or join a new Game.
 type MsgGameRequest struct {
 	// TODO: allow client to defined desired parameters.
-	C *Client
+	C        *Client
+	WordList string // The name of the word list asked for, if any.
+	Language string // The name of the language pack asked for, if any.
+	Rule     string // The name of the scoring rule asked for, if any.
+	Layout   string // The name of the board layout asked for, if any.
+	Words    string // The name of the preset of word rules asked for, if any.
+	// Islands asks for every valid island to count, with IslandPenalty points for each one
+	// after the first.
+	Islands       bool
+	IslandPenalty int
+	joined        chan gameAssignment // Receives the game assigned, which the client then joins.
+}
+
+// A gameAssignment answers a MsgGameRequest with the game to join, or why there is none.
+type gameAssignment struct {
+	game *Game
+	err  error
 }
 
 type WebsocketConn interface {
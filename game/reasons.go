@@ -0,0 +1,116 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Reasons maps each tile on a board to an explanation of how it was scored.
+type Reasons map[Vec]string
+
+// A TileReason is the marshalled form of a single entry in Reasons.
+type TileReason struct {
+	Loc    Vec
+	Reason string
+}
+
+func (r Reasons) MarshalJSON() ([]byte, error) {
+	rs := []TileReason{}
+	for v, reason := range r {
+		rs = append(rs, TileReason{v, reason})
+	}
+	sort.Slice(rs, func(i, j int) bool { return rs[i].Loc.less(rs[j].Loc) })
+	return json.Marshal(rs)
+}
+
+// joinWords returns the values of the given words as a readable list.
+func joinWords(ws []Word) string {
+	var vs []string
+	for _, w := range ws {
+		vs = append(vs, w.Value)
+	}
+	return strings.Join(vs, " and ")
+}
+
+// joinNonwords returns the values of the given nonwords, with why each was rejected, as a
+// readable list.
+func joinNonwords(ws []Word) string {
+	var vs []string
+	for _, w := range ws {
+		vs = append(vs, fmt.Sprintf("%v (%v)", w.Value, w.Reason))
+	}
+	return strings.Join(vs, " and ")
+}
+
+// wordsContaining returns the words which include any of the given tiles.
+func wordsContaining(ws []Word, tiles TileSet) []Word {
+	var result []Word
+	for _, w := range ws {
+		for v := range w.tiles {
+			if tiles.contains(v) {
+				result = append(result, w)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// explain returns a reason for every tile on the board, based on how it was scored.
+func (s *Scorable) explain(board TileSet) Reasons {
+	reasons := make(Reasons)
+	kept := s.kept
+	all := append(append([]Word{}, s.words...), s.nonwords...)
+
+	for v := range s.valid {
+		reasons[v] = "part of " + joinWords(wordsContaining(kept, TileSet{v: nil}))
+	}
+
+	for v := range s.invalid {
+		if !s.removed.contains(v) {
+			if nonwords := wordsContaining(s.nonwords, TileSet{v: nil}); len(nonwords) > 0 {
+				reasons[v] = "part of non-word " + joinNonwords(nonwords)
+			} else {
+				reasons[v] = "not part of any word"
+			}
+			continue
+		}
+		// Find the kept words which cross any run this tile was dropped from.
+		crossed := make(TileSet)
+		for _, w := range wordsContaining(all, TileSet{v: nil}) {
+			crossed.union(w.tiles)
+		}
+		if helped := wordsContaining(kept, crossed); len(helped) > 0 {
+			reasons[v] = fmt.Sprintf("dropped so that %v would be valid", joinWords(helped))
+		} else {
+			reasons[v] = "dropped so that the rest of the board would be valid"
+		}
+	}
+
+	main := s.score()
+	for _, comp := range s.unconnected.findDisjointSets() {
+		reason := "not connected to the main component"
+		if diff := main - comp.points(); diff > 0 {
+			reason = fmt.Sprintf("in a disconnected component worth %v pts less than the main one", diff)
+		} else if diff == 0 {
+			reason = "in a disconnected component worth the same as the main one"
+		}
+		for v := range comp {
+			reasons[v] = reason
+		}
+	}
+
+	for v := range s.clashing {
+		reasons[v] = "in an island whose words break the word rules alongside a better island"
+	}
+
+	// Every tile on the board should have been covered above.
+	for v := range board {
+		if _, ok := reasons[v]; !ok {
+			reasons[v] = "not scored"
+		}
+	}
+	return reasons
+}
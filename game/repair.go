@@ -0,0 +1,134 @@
+package game
+
+import (
+	"log"
+	"time"
+
+	"github.com/kathrelkeld/speed-scrabble/msg"
+)
+
+// repair sends the moves which make the board the player sent valid and connected, if the
+// game allows repairs.  Each repair is shown in the player's result.
+func (g *Game) repair(c *Client, d []byte) {
+	if !g.config.Repairs {
+		c.sendSocketMsg(msg.Error, "Error: board repair is not allowed in this game")
+		return
+	}
+	board, err := parseBoard(d, c.protocol, g.tiles[:c.servedCnt], g.config)
+	if err != nil {
+		c.sendSocketMsg(msg.Error, "Error: "+err.Error())
+		return
+	}
+	moves := repairBoard(board, g.config)
+	if len(moves) == 0 {
+		c.sendSocketMsg(msg.Error, "Error: board is already valid")
+		return
+	}
+	c.repairs++
+	log.Println("Game", g.Name, "repaired the board of", c.Name, "with", len(moves), "moves")
+	c.sendSocketMsg(msg.Repair, msg.RepairData{Moves: moves})
+}
+
+// repairBoard returns moves which make the board valid and connected, keeping in place the
+// most tiles which already make a valid connected board.  Each other tile is moved just
+// once: next to the kept tiles if it makes only words there, or else back to the tray.  As
+// many tiles as can be found a square stay on the board.
+func repairBoard(board TileSet, cfg Config) []msg.RepairMove {
+	wc := newWordChecker(cfg.Dict, cfg.Words, cfg.Language)
+	wc.voted = cfg.Accepted
+	kept := make(TileSet)
+	for _, c := range board.findDisjointSets() {
+		if sub := c.largestSubset(wc, scoreBudget); sub != nil && len(sub.kept) > len(kept) {
+			kept = sub.kept.copy()
+		}
+	}
+
+	r := &relocation{board: board, wc: wc, cfg: cfg, deadline: time.Now().Add(scoreBudget)}
+	for _, v := range board.sortedVecs() {
+		if !kept.contains(v) {
+			r.from = append(r.from, v)
+		}
+	}
+	r.to = make([]*Vec, len(r.from))
+	r.best = make([]*Vec, len(r.from))
+	r.search(0, kept, 0)
+	if r.timedOut {
+		log.Println("Ran out of time relocating", len(r.from), "tiles")
+	}
+
+	var moves []msg.RepairMove
+	for i, v := range r.from {
+		t := board[v]
+		m := msg.RepairMove{From: msg.PlacedTile{X: v.X, Y: v.Y, Value: t.Value, ID: t.ID}}
+		if to := r.best[i]; to != nil {
+			m.To = &msg.PlacedTile{X: to.X, Y: to.Y, Value: t.Value, ID: t.ID}
+		}
+		moves = append(moves, m)
+	}
+	return moves
+}
+
+// A relocation is a depth first search for squares for the tiles which are not kept, which
+// keeps as many of them on the board as it can.  Each tile, in reading order, tries every
+// square it may move to before going back to the tray, so the first board found places
+// each tile on the first square it can, and later ones only replace it by placing more.
+type relocation struct {
+	board      TileSet
+	wc         *wordChecker
+	cfg        Config
+	from       []Vec  // The squares of the tiles to move, in reading order.
+	to         []*Vec // Where each tile moves in the current branch, or nil for the tray.
+	best       []*Vec // Where each tile moves in the board which placed the most.
+	bestPlaced int
+	deadline   time.Time
+	timedOut   bool
+}
+
+// search moves the tiles from the ith on, beside the kept tiles, of which placed were moved.
+func (r *relocation) search(i int, kept TileSet, placed int) {
+	if placed+len(r.from)-i <= r.bestPlaced {
+		return
+	}
+	if r.timedOut || time.Now().After(r.deadline) {
+		r.timedOut = true
+		return
+	}
+	if i == len(r.from) {
+		copy(r.best, r.to)
+		r.bestPlaced = placed
+		return
+	}
+	t := r.board[r.from[i]]
+	for _, to := range squaresFor(r.board, kept, t, r.wc, r.cfg) {
+		to := to
+		kept[to] = t
+		r.to[i] = &to
+		r.search(i+1, kept, placed+1)
+		delete(kept, to)
+	}
+	r.to[i] = nil
+	r.search(i+1, kept, placed)
+}
+
+// squaresFor returns every empty square beside the kept tiles where t makes only valid
+// words, in reading order of the kept tiles.  Squares with a tile on the original board are
+// skipped, so that each move lands on a free square whichever tiles have moved before it.
+func squaresFor(board, kept TileSet, t *Tile, wc *wordChecker, cfg Config) []Vec {
+	var squares []Vec
+	tried := make(map[Vec]bool)
+	for _, v := range kept.sortedVecs() {
+		for _, d := range []Vec{{0, -1}, {-1, 0}, {1, 0}, {0, 1}} {
+			to := v.add(d)
+			if tried[to] || !cfg.inBounds(to) || board.contains(to) || kept.contains(to) {
+				continue
+			}
+			tried[to] = true
+			next := kept.copy()
+			next[to] = t
+			if onlyWords(next.findWords(wc)) {
+				squares = append(squares, to)
+			}
+		}
+	}
+	return squares
+}
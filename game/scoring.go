@@ -0,0 +1,210 @@
+package game
+
+// A ScoringRule decides how many points a board is worth.
+type ScoringRule interface {
+	// Name identifies this rule to players.
+	Name() string
+	// Pts returns the points for a board, given its best scorable portion, every tile the
+	// player was served, and the premium squares of the board.  The tiles served must change
+	// the points of every portion of a board alike, as the best portion is found without them.
+	Pts(sc *Scorable, served []Tile, layout Layout) int
+	// LowerIsBetter returns whether a lower number of points beats a higher one.
+	LowerIsBetter() bool
+}
+
+// A boundedRule can say the best points a board made of only some of the given tiles could
+// get, with no tiles served, so that searches may skip boards which cannot win.
+type boundedRule interface {
+	bestPts(s TileSet, layout Layout) int
+}
+
+// ScoringRules lists every available rule by name.
+var ScoringRules = map[string]ScoringRule{
+	"leftover":            leftoverRule{},
+	"length-bonus":        lengthBonusRule{},
+	"tile-count":          tileCountRule{},
+	"letters-times-words": lettersTimesWordsRule{},
+	"words":               wordsRule{},
+}
+
+// servedPts returns the sum of the points of the given tiles.
+func servedPts(served []Tile) int {
+	pts := 0
+	for _, t := range served {
+		pts += t.Points
+	}
+	return pts
+}
+
+// leftoverRule counts the points of every tile which was not used; lower is better.
+type leftoverRule struct{}
+
+func (leftoverRule) Name() string        { return "leftover" }
+func (leftoverRule) LowerIsBetter() bool { return true }
+
+func (leftoverRule) Pts(sc *Scorable, served []Tile, layout Layout) int {
+	return servedPts(served) - sc.score()
+}
+
+func (leftoverRule) bestPts(s TileSet, layout Layout) int {
+	return -s.points()
+}
+
+// lengthBonusWordLen is the shortest word which earns a bonus under lengthBonusRule.
+const lengthBonusWordLen = 5
+
+// lengthBonusRule counts the points of every tile used, plus a bonus equal to the length of
+// each long word; higher is better.
+type lengthBonusRule struct{}
+
+func (lengthBonusRule) Name() string        { return "length-bonus" }
+func (lengthBonusRule) LowerIsBetter() bool { return false }
+
+func (lengthBonusRule) Pts(sc *Scorable, served []Tile, layout Layout) int {
+	pts := sc.score()
+	for _, w := range sc.kept {
+		if len(w.tiles) >= lengthBonusWordLen {
+			pts += len(w.tiles)
+		}
+	}
+	return pts
+}
+
+// bestPts counts each long run as a long word, as the words split from a run are no longer.
+func (lengthBonusRule) bestPts(s TileSet, layout Layout) int {
+	pts := s.points()
+	for _, w := range s.runs() {
+		if len(w.tiles) >= lengthBonusWordLen {
+			pts += len(w.tiles)
+		}
+	}
+	return pts
+}
+
+// tileCountRule counts the tiles which were not used, regardless of their letters; lower
+// is better.
+type tileCountRule struct{}
+
+func (tileCountRule) Name() string        { return "tile-count" }
+func (tileCountRule) LowerIsBetter() bool { return true }
+
+func (tileCountRule) Pts(sc *Scorable, served []Tile, layout Layout) int {
+	return len(served) - len(sc.valid)
+}
+
+func (tileCountRule) bestPts(s TileSet, layout Layout) int {
+	return -len(s)
+}
+
+// lettersTimesWordsRule multiplies the number of tiles used by the number of words they
+// make; higher is better.
+type lettersTimesWordsRule struct{}
+
+func (lettersTimesWordsRule) Name() string        { return "letters-times-words" }
+func (lettersTimesWordsRule) LowerIsBetter() bool { return false }
+
+func (lettersTimesWordsRule) Pts(sc *Scorable, served []Tile, layout Layout) int {
+	return len(sc.valid) * len(sc.kept)
+}
+
+// bestPts splits each run into as many words as it could hold, each of two tiles with a gap
+// between them.
+func (lettersTimesWordsRule) bestPts(s TileSet, layout Layout) int {
+	words := 0
+	for _, w := range s.runs() {
+		words += (len(w.tiles) + 1) / 3
+	}
+	return len(s) * words
+}
+
+// wordsRule counts the value of each word separately, as in Scrabble, so tiles at a
+// crossing count twice and premium squares apply; higher is better.
+type wordsRule struct{}
+
+func (wordsRule) Name() string        { return "words" }
+func (wordsRule) LowerIsBetter() bool { return false }
+
+func (wordsRule) Pts(sc *Scorable, served []Tile, layout Layout) int {
+	pts := 0
+	for _, w := range sc.kept {
+		pts += w.pts(layout)
+	}
+	return pts
+}
+
+// bestPts counts each run as a word, as the words split from a run are worth no more.
+func (wordsRule) bestPts(s TileSet, layout Layout) int {
+	pts := 0
+	for _, w := range s.runs() {
+		pts += w.pts(layout)
+	}
+	return pts
+}
+
+// A Config holds the settings chosen for a single Game.
+type Config struct {
+	// Rule decides how many points each board is worth.
+	Rule ScoringRule
+	// Layout places premium squares on each player's board.  Only rules which count words
+	// separately, such as "words", use it.
+	Layout Layout
+	// LayoutName is the name of Layout in Layouts, or empty for none.
+	LayoutName string
+	// BoardSize is the number of columns and rows on each player's board.
+	BoardSize Vec
+	// MaxBoardTiles is the most tiles a player may submit on a single board.
+	MaxBoardTiles int
+	// Dict holds every valid word.
+	Dict Dictionary
+	// WordList is the name of Dict, shown to players.
+	WordList string
+	// Defs holds the meaning of the words in Dict, if known.
+	Defs Definitions
+	// DictVersion counts the times the word lists had been loaded when Dict was chosen.
+	DictVersion int
+	// Words decide which runs of tiles count as words, on top of Dict.
+	Words WordRules
+	// WordsName is the name of Words in WordRulePresets, or empty for normal.
+	WordsName string
+	// Accepted holds words missing from Dict which the players voted to count anyway.
+	Accepted map[string]bool
+	// Language decides which tiles are in the bag.  Nil means English.
+	Language *LanguagePack
+	// ScoreAllIslands counts every valid group of connected tiles, instead of only the best.
+	ScoreAllIslands bool
+	// IslandPenalty is taken off the score for each island after the first.
+	IslandPenalty int
+	// Hints decide whether players may ask for hints, and what they cost.
+	Hints HintRules
+	// Repairs lets players ask for tile moves or removals which make their board valid and
+	// connected.
+	Repairs bool
+}
+
+// detached returns a copy of these settings which may be used away from the game goroutine,
+// as votes may still change the words accepted.
+func (cfg Config) detached() Config {
+	accepted := make(map[string]bool)
+	for w := range cfg.Accepted {
+		accepted[w] = true
+	}
+	cfg.Accepted = accepted
+	return cfg
+}
+
+// inBounds returns whether the given coordinates are on a player's board.
+func (cfg Config) inBounds(v Vec) bool {
+	return v.X >= 0 && v.X < cfg.BoardSize.X && v.Y >= 0 && v.Y < cfg.BoardSize.Y
+}
+
+// DefaultConfig returns the settings used with the given dictionary when a game does not
+// ask for any others.
+func DefaultConfig(dict Dictionary) Config {
+	return Config{
+		Rule:          leftoverRule{},
+		Dict:          dict,
+		BoardSize:     Vec{16, 16},
+		MaxBoardTiles: english.bagSize(),
+		Language:      english,
+	}
+}
@@ -0,0 +1,191 @@
+package game
+
+import (
+	"log"
+	"math"
+	"time"
+)
+
+// scoreBudget is how long the search for the best subset of a single component may run.
+var scoreBudget = time.Second
+
+// A subset is a candidate set of tiles to keep from a component, along with the tiles that
+// were removed to break up nonwords.
+type subset struct {
+	kept    TileSet
+	removed TileSet
+	words   []Word // The words made by the kept tiles.
+	pts     int    // The weight of the kept tiles.
+}
+
+// A subsetWeight says how much each valid subset of a component is worth to a search, where
+// heavier subsets are preferred.
+type subsetWeight struct {
+	// of weighs a valid subset, given the words its tiles make.
+	of func(kept TileSet, words []Word) int
+	// bound returns the most that any subset of the given tiles could weigh.
+	bound func(s TileSet) int
+}
+
+// sizeWeight weighs subsets by their number of tiles.
+var sizeWeight = subsetWeight{
+	of:    func(kept TileSet, words []Word) int { return len(kept) },
+	bound: TileSet.size,
+}
+
+// ruleWeight weighs subsets by the points the rule gives a board made of only those tiles,
+// negated if lower is better.  No tiles are served, as every rule counts the tiles served
+// alike for every subset.  Rules which cannot bound their points leave no branch pruned.
+func ruleWeight(rule ScoringRule, layout Layout) subsetWeight {
+	sign := 1
+	if rule.LowerIsBetter() {
+		sign = -1
+	}
+	w := subsetWeight{
+		of: func(kept TileSet, words []Word) int {
+			return sign * rule.Pts(&Scorable{valid: kept, kept: words}, nil, layout)
+		},
+		bound: func(TileSet) int { return math.MaxInt32 },
+	}
+	if b, ok := rule.(boundedRule); ok {
+		w.bound = func(s TileSet) int { return sign * b.bestPts(s, layout) }
+	}
+	return w
+}
+
+// betterThan returns whether a should be preferred over b.  Ties on weight are broken by
+// keeping more tiles, then by removing fewer, then by comparing coordinates, so that the
+// result never depends on map iteration order.
+func (a *subset) betterThan(b *subset) bool {
+	if a.pts != b.pts {
+		return a.pts > b.pts
+	}
+	if len(a.kept) != len(b.kept) {
+		return len(a.kept) > len(b.kept)
+	}
+	if len(a.removed) != len(b.removed) {
+		return len(a.removed) < len(b.removed)
+	}
+	if c := compareVecs(a.kept.sortedVecs(), b.kept.sortedVecs()); c != 0 {
+		return c < 0
+	}
+	return compareVecs(a.removed.sortedVecs(), b.removed.sortedVecs()) < 0
+}
+
+// compareVecs lexicographically compares two equal length sorted slices of coordinates.
+func compareVecs(a, b []Vec) int {
+	for i := range a {
+		if a[i] != b[i] {
+			if a[i].less(b[i]) {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// A subsetSearch is a branch and bound search over the tiles of a component.
+// Every nonword must lose at least one of its tiles, as must every pair of words which clash,
+// so the search branches on which of those tiles to remove.  Tiles passed over in earlier
+// branches are required to stay, so that no subset is visited twice.  A branch is abandoned
+// once its weight can no longer beat the best subset found so far.
+type subsetSearch struct {
+	wc       *wordChecker
+	weight   subsetWeight
+	deadline time.Time
+	timedOut bool
+	best     *subset
+}
+
+// bestSubset returns the heaviest subset of these tiles which is connected and in which every
+// run of two or more tiles is a word.  If the budget runs out, the best subset found so far
+// is returned.  Returns nil if no subset was found.
+func (s TileSet) bestSubset(wc *wordChecker, weight subsetWeight, budget time.Duration) *subset {
+	ss := &subsetSearch{wc: wc, weight: weight, deadline: time.Now().Add(budget)}
+	ss.search(s, TileSet{}, TileSet{})
+	if ss.timedOut {
+		log.Println("Ran out of time searching for the best subset of", len(s), "tiles")
+	}
+	return ss.best
+}
+
+// largestSubset returns the subset of these tiles with the most tiles which is connected and
+// in which every run of two or more tiles is a word.
+func (s TileSet) largestSubset(wc *wordChecker, budget time.Duration) *subset {
+	return s.bestSubset(wc, sizeWeight, budget)
+}
+
+// canImprove returns whether some subset of these tiles could be preferred over the best
+// subset found so far.
+func (ss *subsetSearch) canImprove(s TileSet) bool {
+	if ss.best == nil {
+		return true
+	}
+	pts := ss.weight.bound(s)
+	return pts > ss.best.pts || pts == ss.best.pts && len(s) >= len(ss.best.kept)
+}
+
+// search looks for subsets of s which keep all of the required tiles.
+// The removed tiles are those which have been dropped on the way to s.
+func (ss *subsetSearch) search(s, required, removed TileSet) {
+	if ss.timedOut || time.Now().After(ss.deadline) {
+		ss.timedOut = true
+		return
+	}
+	for _, c := range s.findDisjointSets() {
+		if len(c) < 2 || !c.containsAll(required) || !ss.canImprove(c) {
+			continue
+		}
+		words := c.findWords(ss.wc)
+		tiles, ok := pickNonword(words, required)
+		if !ok {
+			candidate := &subset{kept: c, removed: removed, words: words, pts: ss.weight.of(c, words)}
+			if ss.best == nil || candidate.betterThan(ss.best) {
+				ss.best = candidate
+			}
+			continue
+		}
+		keep := required.copy()
+		for _, v := range tiles.sortedVecs() {
+			if keep.contains(v) {
+				continue
+			}
+			next := c.copy()
+			delete(next, v)
+			nextRemoved := removed.copy()
+			nextRemoved[v] = c[v]
+			ss.search(next, keep, nextRemoved)
+			keep[v] = c[v]
+		}
+	}
+}
+
+// pickNonword returns the tiles of the nonword among these words with the fewest tiles which
+// are not required, if there are any nonwords.  For a word which clashes with another, the
+// tiles of both are returned.
+func pickNonword(words []Word, required TileSet) (TileSet, bool) {
+	var result TileSet
+	fewest := 0
+	for _, w := range words {
+		if w.isWord {
+			continue
+		}
+		tiles := w.tiles
+		if w.clash != nil {
+			tiles = w.tiles.copy()
+			tiles.union(w.clash)
+		}
+		free := 0
+		for v := range tiles {
+			if !required.contains(v) {
+				free++
+			}
+		}
+		if result == nil || free < fewest {
+			result = tiles
+			fewest = free
+		}
+	}
+	return result, result != nil
+}
@@ -0,0 +1,105 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/kathrelkeld/speed-scrabble/msg"
+)
+
+// parseBoard decodes a board sent by a player in the given protocol.  The board must fit
+// within the bounds set by cfg.  Boards sent with msg.ProtocolSparse must also only use
+// tiles which were served, each at most once.
+func parseBoard(d []byte, protocol int, served []Tile, cfg Config) (TileSet, error) {
+	if protocol == msg.ProtocolSparse {
+		var placed []msg.PlacedTile
+		if err := json.Unmarshal(d, &placed); err != nil {
+			return nil, err
+		}
+		return parseSparseBoard(placed, served, cfg)
+	}
+	var board Board
+	if err := json.Unmarshal(d, &board); err != nil {
+		return nil, err
+	}
+	s := board.setOfAllTiles()
+	if len(s) > cfg.MaxBoardTiles {
+		return nil, fmt.Errorf("board has %v tiles; at most %v are allowed", len(s), cfg.MaxBoardTiles)
+	}
+	for v := range s {
+		if !cfg.inBounds(v) {
+			return nil, fmt.Errorf("tile at %v is outside the %v by %v board", v, cfg.BoardSize.X, cfg.BoardSize.Y)
+		}
+	}
+	return s, nil
+}
+
+// matchServed returns the board with each tile swapped for the served tile it stands for,
+// matched by ID and value where possible and otherwise by value alone, as boards sent with
+// msg.ProtocolGrid may carry any IDs.  It fails if the board holds a tile never served.
+func matchServed(board TileSet, served []Tile) (TileSet, error) {
+	byID := make(map[int]Tile)
+	for _, t := range served {
+		byID[t.ID] = t
+	}
+	used := make(map[int]bool)
+	result := make(TileSet, len(board))
+	var unmatched []Vec
+	for _, v := range board.sortedVecs() {
+		if t, ok := byID[board[v].ID]; ok && t.Value == board[v].Value && !used[t.ID] {
+			used[t.ID] = true
+			result[v] = &t
+		} else {
+			unmatched = append(unmatched, v)
+		}
+	}
+	for _, v := range unmatched {
+		for i := range served {
+			if t := served[i]; !used[t.ID] && t.Value == board[v].Value {
+				used[t.ID] = true
+				result[v] = &t
+				break
+			}
+		}
+		if result[v] == nil {
+			return nil, fmt.Errorf("tile %v at %v was never served", board[v].Value, v)
+		}
+	}
+	return result, nil
+}
+
+// parseSparseBoard returns the tiles placed by a player, using the letters and points of
+// the tiles as they were served.
+func parseSparseBoard(placed []msg.PlacedTile, served []Tile, cfg Config) (TileSet, error) {
+	if len(placed) > cfg.MaxBoardTiles {
+		return nil, fmt.Errorf("board has %v tiles; at most %v are allowed", len(placed), cfg.MaxBoardTiles)
+	}
+	byID := make(map[int]Tile)
+	for _, t := range served {
+		byID[t.ID] = t
+	}
+	used := make(map[int]bool)
+	s := make(TileSet)
+	for _, p := range placed {
+		v := Vec{p.X, p.Y}
+		if !cfg.inBounds(v) {
+			return nil, fmt.Errorf("tile at %v is outside the %v by %v board", v, cfg.BoardSize.X, cfg.BoardSize.Y)
+		}
+		if s.contains(v) {
+			return nil, fmt.Errorf("more than one tile at %v", v)
+		}
+		t, ok := byID[p.ID]
+		if !ok {
+			return nil, fmt.Errorf("tile %v at %v was never served", p.ID, v)
+		}
+		if used[p.ID] {
+			return nil, fmt.Errorf("tile %v was placed more than once", p.ID)
+		}
+		if t.Value != p.Value {
+			return nil, fmt.Errorf("tile %v at %v is %v, not %v", p.ID, v, t.Value, p.Value)
+		}
+		used[p.ID] = true
+		s[v] = &t
+	}
+	return s, nil
+}
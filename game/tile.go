@@ -7,17 +7,20 @@ import (
 type Tile struct {
 	Value  string
 	Points int
+	ID     int // Position of this tile in the game's shuffled tiles.
 }
 
 func (t Tile) String() string {
 	return t.Value
 }
 
-func newTiles() []Tile {
+// newTiles returns a full bag of this language's tiles, shuffled.
+func (p *LanguagePack) newTiles() []Tile {
+	p = p.orEnglish()
 	var tiles []Tile
-	for k, v := range freqMap {
+	for k, v := range p.Tiles {
 		for j := 0; j < v; j++ {
-			tile := Tile{Value: k, Points: pointValues[k]}
+			tile := Tile{Value: k, Points: p.Points[k]}
 			tiles = append(tiles, tile)
 		}
 	}
@@ -25,6 +28,20 @@ func newTiles() []Tile {
 		j := rand.Intn(i + 1)
 		tiles[i], tiles[j] = tiles[j], tiles[i]
 	}
+	for i := range tiles {
+		tiles[i].ID = i
+	}
+	return tiles
+}
+
+// MakeTiles returns a hand of this language's tiles with the given values, numbered in
+// order.
+func (p *LanguagePack) MakeTiles(values ...string) []Tile {
+	p = p.orEnglish()
+	var tiles []Tile
+	for i, v := range values {
+		tiles = append(tiles, Tile{Value: v, Points: p.Points[v], ID: i})
+	}
 	return tiles
 }
 
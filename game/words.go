@@ -0,0 +1,131 @@
+package game
+
+import (
+	"fmt"
+	"strings"
+)
+
+// WordRules decide which runs of tiles count as words, on top of the dictionary.
+type WordRules struct {
+	// MinLength is the fewest tiles in a word.  Words always need at least 2.
+	MinLength int
+	// NoRepeats allows each word only once per board.
+	NoRepeats bool
+	// NoPlurals disallows a word ending in S alongside the same word without it.
+	NoPlurals bool
+	// BannedLetters may not appear in any word.
+	BannedLetters []string
+}
+
+// WordRulePresets lists the standard sets of word rules by name.
+var WordRulePresets = map[string]WordRules{
+	"normal": {},
+	"hard":   {MinLength: 3, NoRepeats: true, NoPlurals: true},
+}
+
+// notInDictionary is the reason given for words which players may challenge.
+const notInDictionary = "not in the dictionary"
+
+// A wordChecker decides which runs of tiles are words for a single game.
+type wordChecker struct {
+	dict    Dictionary
+	rules   WordRules
+	banned  map[string]bool
+	multi   []string        // The language's multi-letter tiles, longest first.
+	voted   map[string]bool // Words the players voted to accept, though not in dict.
+	version int             // Version of the house rules in dict, if any, when the checker was made.
+	// runs holds every run of tiles checked so far, by where it is and what its tiles are,
+	// as searches check the same runs many times over.
+	runs map[string]Word
+}
+
+// newWordChecker returns a checker for words in the given language, where nil means English.
+func newWordChecker(dict Dictionary, rules WordRules, lang *LanguagePack) *wordChecker {
+	wc := &wordChecker{
+		dict:   dict,
+		rules:  rules,
+		banned: make(map[string]bool),
+		multi:  lang.multiLetterTiles(),
+		runs:   make(map[string]Word),
+	}
+	for _, l := range rules.BannedLetters {
+		wc.banned[strings.ToUpper(l)] = true
+	}
+	if hd, ok := dict.(*houseDict); ok {
+		wc.version = hd.house.version()
+	}
+	return wc
+}
+
+// check sets whether this word is valid on its own, along with the reason if it is not.
+func (wc *wordChecker) check(w *Word) {
+	w.isWord = false
+	if n := wc.rules.MinLength; len(w.tiles) < n {
+		w.Reason = fmt.Sprintf("shorter than %v letters", n)
+		return
+	}
+	for _, v := range w.tiles.sortedVecs() {
+		if l := w.tiles[v].Value; wc.banned[l] {
+			w.Reason = "uses banned letter " + l
+			return
+		}
+	}
+	if l, ok := misspelling(w.letters(), wc.multi); ok {
+		w.Reason = "spells " + l + " without the " + l + " tile"
+		return
+	}
+	if hd, ok := wc.dict.(*houseDict); ok && hd.banned(w.Value) {
+		w.Reason = "banned by house rules"
+		return
+	}
+	if !wc.dict.Contains(w.Value) && !wc.voted[w.Value] {
+		w.Reason = notInDictionary
+		return
+	}
+	w.isWord = true
+	w.Reason = ""
+}
+
+// checkBoard marks any words which are only invalid because of another word on the same
+// board.  Each such word records the tiles of the word it clashes with.
+func (wc *wordChecker) checkBoard(words []Word) {
+	seen := make(map[string]int)
+	for i := range words {
+		w := &words[i]
+		if !w.isWord {
+			continue
+		}
+		if j, ok := seen[w.Value]; ok && wc.rules.NoRepeats {
+			w.isWord = false
+			w.Reason = "repeats " + w.Value
+			w.clash = words[j].tiles
+			continue
+		}
+		seen[w.Value] = i
+	}
+	if !wc.rules.NoPlurals {
+		return
+	}
+	for i := range words {
+		w := &words[i]
+		if !w.isWord || !strings.HasSuffix(w.Value, "S") {
+			continue
+		}
+		single := strings.TrimSuffix(w.Value, "S")
+		if j, ok := seen[single]; ok && words[j].isWord {
+			w.isWord = false
+			w.Reason = "plural of " + single
+			w.clash = words[j].tiles
+		}
+	}
+}
+
+// onlyWords returns whether every one of the given runs of tiles is a valid word.
+func onlyWords(words []Word) bool {
+	for _, w := range words {
+		if !w.isWord {
+			return false
+		}
+	}
+	return true
+}
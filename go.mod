@@ -1,5 +1,5 @@
 module github.com/kathrelkeld/speed-scrabble
 
-go 1.15
+go 1.16
 
 require github.com/gorilla/websocket v1.4.2
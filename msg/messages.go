@@ -27,6 +27,36 @@ const (
 	OutOfTiles
 	PlayerJoined
 	Result
+	// Define asks for the definition of a word.
+	// Data: the word from players, or DefinitionData from the server.
+	Define
+	// Challenge asks the other players to vote on accepting a word not in the dictionary.
+	// Data: the word.
+	Challenge
+	// Vote asks a player to vote on a challenged word, and carries their answer back.
+	// Data: VoteData from the server, or BallotData from players.
+	Vote
+	// VoteResult tells every player how a vote ended.
+	// Data: VoteResultData.
+	VoteResult
+	// AddBot asks for a computer player to join the sender's game.
+	// Data: the name of the bot's level, such as "easy".
+	AddBot
+	// RemoveBot asks for a computer player to leave the sender's game.
+	// Data: none.
+	RemoveBot
+	// Hint asks for a word the player could add to their board.
+	// Data: the player's current board, in their protocol, or HintData from the server.
+	Hint
+	// Analysis shows a player what they could have made from their hand, after Result.
+	// Data: AnalysisData.
+	Analysis
+	// Anagrams asks for every word which can be made from a rack of tiles.
+	// Data: AnagramRequest from players, or AnagramData from the server.
+	Anagrams
+	// Repair asks for moves which make the player's board valid again.
+	// Data: the player's current board, in their protocol, or RepairData from the server.
+	Repair
 )
 
 var TypeToString = map[Type]string{
@@ -44,6 +74,16 @@ var TypeToString = map[Type]string{
 	OutOfTiles:   "outOfTiles",
 	PlayerJoined: "playerJoined",
 	Result:       "result",
+	Define:       "define",
+	Challenge:    "challenge",
+	Vote:         "vote",
+	VoteResult:   "voteResult",
+	AddBot:       "addBot",
+	RemoveBot:    "removeBot",
+	Hint:         "hint",
+	Analysis:     "analysis",
+	Anagrams:     "anagrams",
+	Repair:       "repair",
 }
 
 func (mt Type) String() string {
@@ -68,7 +108,166 @@ func NewSocketData(t Type, d interface{}) ([]byte, error) {
 	return b, nil
 }
 
+// Protocols describe how a player sends their board.  A player asks for a protocol when
+// joining, and the server replies with the one it will accept.
+const (
+	// ProtocolGrid sends the whole board as rows of tiles, with nulls for empty squares.
+	ProtocolGrid = 1
+	// ProtocolSparse sends only the placed tiles, as a list of PlacedTile.
+	ProtocolSparse = 2
+)
+
+// JoinGameData is sent by a player asking to join a game.
+// Older players send only their name as a string, which implies ProtocolGrid.
+type JoinGameData struct {
+	Name     string
+	Protocol int
+	WordList string // The word list to play with; empty for the language's default.
+	Language string // The language to play in; empty for English.
+	Rule     string // The scoring rule to play with; empty for the server's default.
+	Layout   string // The layout of premium squares to play with; empty for none.
+	Words    string // The preset of word rules to play with, such as "hard"; empty for normal.
+	// Islands counts every valid island of tiles, as a practice variant, with IslandPenalty
+	// points taken off for each island after the first.
+	Islands       bool
+	IslandPenalty int
+	Bot           bool // Whether the player is a computer, which is not asked to vote.
+}
+
+// PlayerJoinedData is sent to a player once they have joined a game.
+type PlayerJoinedData struct {
+	Protocol int // The protocol the server accepts boards in.
+}
+
+// A PlacedTile is a single tile on a board sent with ProtocolSparse.
+type PlacedTile struct {
+	X     int
+	Y     int
+	Value string
+	ID    int // The ID of the tile as it was served.
+}
+
 type GameInfoData struct {
 	GameName    string
 	PlayerNames []string
+	Premiums    []PremiumSquare
+	WordList    string // The name of the word list used to check words.
+	Language    string // The name of the language the tiles are in.
+	Words       string // The name of the preset of word rules, or empty for normal.
+}
+
+// A PremiumSquare is a board square which multiplies the value of a letter or word.
+type PremiumSquare struct {
+	X    int
+	Y    int
+	Kind string // One of "DL", "TL", "DW" or "TW".
+}
+
+// ResultData is sent to every player when a round ends.
+type ResultData struct {
+	Rule        string         // The name of the scoring rule used.
+	Players     []PlayerResult // Players in order from best to worst score.
+	Awards      []Award
+	Definitions map[string]string // Short definitions of the words played, where known.
+}
+
+// DefinitionData is the answer to a Define request.
+type DefinitionData struct {
+	Word       string
+	Definition string
+	Found      bool
+}
+
+// A PlayerResult is the final score of a single player.
+type PlayerResult struct {
+	Name    string
+	Pts     int
+	Win     bool
+	Hints   int // Number of hints taken this round, whose cost is already in Pts.
+	Repairs int // Number of times the board was repaired this round.
+}
+
+// An Award is a badge given to the players with the best board in some category.
+type Award struct {
+	Name    string   // E.g. "Longest word".
+	Players []string // Every player tied for this award.
+	Detail  string   // What earned the award, e.g. the word.
+}
+
+// VoteData asks a player whether a challenged word should count.
+type VoteData struct {
+	Word       string
+	Challenger string // The name of the player who challenged the word.
+}
+
+// BallotData is a player's vote on a challenged word.
+type BallotData struct {
+	Word   string
+	Accept bool
+}
+
+// VoteResultData is sent to every player once a vote on a challenged word ends.
+type VoteResultData struct {
+	Word     string
+	Accepted bool // Whether the word now counts for the rest of the game.
+	For      int
+	Against  int
+}
+
+// HintData suggests a word for a player to add to their board.
+type HintData struct {
+	Word  string
+	Tiles []PlacedTile // Where to place each tile of the word which is not yet on the board.
+	// Moved is the invalid tile on the board, at its current place, which the hint moves
+	// into the word, if any.
+	Moved *PlacedTile
+	Left  int // The number of hints the player has left this round, or -1 if unlimited.
+}
+
+// AnalysisData shows a player, once a round is over, what they could have made from the
+// tiles they were served.
+type AnalysisData struct {
+	// Board places every tile of the hand in a single winning grid, if one was found.
+	Board   []PlacedTile
+	Longest string      // The longest word which could be made from the hand.
+	Missed  []WordScore // The words worth the most points which the player did not play.
+}
+
+// A WordScore is a word along with the points of its tiles.
+type WordScore struct {
+	Word string
+	Pts  int
+}
+
+// AnagramRequest asks for every word which can be made from a rack of tiles.
+type AnagramRequest struct {
+	Rack string // The tiles to use, with ? for a blank, e.g. "RETAINS?".
+	// Pattern, if any, sets the length of the words and the letters already in place, with
+	// ? for each square to fill from the rack, e.g. "?A??E".
+	Pattern string
+	Sort    string // "points" to list the words worth the most first; otherwise alphabetical.
+}
+
+// AnagramData lists the words which can be made from a rack, longest first.
+type AnagramData struct {
+	Rack    string
+	Pattern string
+	Groups  []AnagramGroup
+}
+
+// An AnagramGroup holds the words of a single length, in tiles.
+type AnagramGroup struct {
+	Length int
+	Words  []WordScore
+}
+
+// RepairData lists the moves, in order, which make a player's board valid and connected.
+type RepairData struct {
+	Moves []RepairMove
+}
+
+// A RepairMove takes a single tile to another square of the board, or back to the tray.
+type RepairMove struct {
+	From PlacedTile  // The tile, at its current place.
+	To   *PlacedTile // Where the tile goes, or nil to send it back to the tray.
 }
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// authorized returns whether the request carries the admin token.  Without a token, every
+// admin request is refused.
+func (s *Server) authorized(req *http.Request) bool {
+	if s.adminToken == "" {
+		return false
+	}
+	token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.adminToken)) == 1
+}
+
+// adminWords edits the house rules.  GET returns every room's lists as JSON.  POST adds and
+// DELETE removes the "word" parameter from the "list" parameter, one of "allow", "ban" or
+// "candidates", of the "room" parameter, or of every room if there is none.  Rooms are named
+// after their games.
+func (s *Server) adminWords(w http.ResponseWriter, req *http.Request) {
+	if !s.authorized(req) {
+		http.Error(w, "not authorized", http.StatusUnauthorized)
+		return
+	}
+	var err error
+	switch req.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		err = s.house.Add(req.FormValue("room"), req.FormValue("list"), req.FormValue("word"))
+	case http.MethodDelete:
+		err = s.house.Remove(req.FormValue("room"), req.FormValue("list"), req.FormValue("word"))
+	default:
+		http.Error(w, "expected GET, POST or DELETE", http.StatusMethodNotAllowed)
+		return
+	}
+	if err != nil {
+		log.Println("Could not edit house rules:", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.house.Lists())
+}
+
+// adminReload starts loading every word list and definitions file again, and returns
+// without waiting for it to finish.  Only POST is accepted.
+func (s *Server) adminReload(w http.ResponseWriter, req *http.Request) {
+	if !s.authorized(req) {
+		http.Error(w, "not authorized", http.StatusUnauthorized)
+		return
+	}
+	if req.Method != http.MethodPost {
+		http.Error(w, "expected POST", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.reload() {
+		http.Error(w, "already reloading", http.StatusConflict)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+	fmt.Fprintln(w, "reloading word lists")
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/kathrelkeld/speed-scrabble/game"
+	"github.com/kathrelkeld/speed-scrabble/msg"
+)
+
+// anagrams returns, as JSON, every word which can be made from the tiles of the "rack"
+// parameter, fitting the "pattern" parameter if any, sorted as the "sort" parameter asks.
+// Words come from the word list named by the "list" parameter, or from the language's list
+// or the default one if there is none, and tiles from the language named by "lang".
+func (s *Server) anagrams(w http.ResponseWriter, req *http.Request) {
+	r := msg.AnagramRequest{
+		Rack:    req.FormValue("rack"),
+		Pattern: req.FormValue("pattern"),
+		Sort:    req.FormValue("sort"),
+	}
+	if r.Rack == "" {
+		http.Error(w, "no rack given", http.StatusBadRequest)
+		return
+	}
+	lang, ok := game.LanguagePacks[req.FormValue("lang")]
+	if !ok && req.FormValue("lang") != "" {
+		http.Error(w, "no language named "+req.FormValue("lang"), http.StatusNotFound)
+		return
+	}
+	list := req.FormValue("list")
+	if list == "" && lang != nil {
+		list = lang.WordList
+	}
+	d := s.ga.Dictionary(list)
+	if d == nil {
+		http.Error(w, "no word list named "+list, http.StatusNotFound)
+		return
+	}
+	result, err := game.FindAnagrams(d, lang, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
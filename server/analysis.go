@@ -0,0 +1,27 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/kathrelkeld/speed-scrabble/game"
+	"github.com/kathrelkeld/speed-scrabble/msg"
+	"github.com/kathrelkeld/speed-scrabble/solver"
+)
+
+// analysisBudget is how long the solver may search for a board using a player's whole hand.
+const analysisBudget = 3 * time.Second
+
+// analyze returns a board using every tile of a player's hand, if the solver finds one in
+// time, along with every word the hand can make.
+func analyze(hand []game.Tile, cfg game.Config) (game.Board, []msg.WordScore) {
+	board, err := solver.Solve(hand, cfg, analysisBudget)
+	if err != nil {
+		log.Println("No board found for analysis:", err)
+	}
+	var words []msg.WordScore
+	for _, w := range solver.Words(hand, cfg) {
+		words = append(words, msg.WordScore{Word: w.Text, Pts: w.Pts})
+	}
+	return board, words
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/kathrelkeld/speed-scrabble/bot"
+	"github.com/kathrelkeld/speed-scrabble/game"
+	"github.com/kathrelkeld/speed-scrabble/msg"
+)
+
+// startBot starts a computer player of the named level in this process, connected to the
+// game through a pipe, and returns the game's end of the pipe.
+func (s *Server) startBot(level string, join msg.JoinGameData) (game.WebsocketConn, error) {
+	l, ok := bot.Levels[level]
+	if !ok {
+		return nil, fmt.Errorf("no bot level %q", level)
+	}
+	n := atomic.AddInt32(&s.bots, 1)
+	name := fmt.Sprintf("%v bot %v", strings.Title(l.Name), n)
+	serverEnd, botEnd := bot.Pipe()
+	b := bot.New(botEnd, name, l, s.ga.GameDictionary)
+	go b.Run(join)
+	return serverEnd, nil
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/kathrelkeld/speed-scrabble/msg"
+)
+
+// define looks up the "word" parameter in the definitions of the word list named by the
+// "list" parameter, or of the default list if there is none, and returns it as JSON.
+func (s *Server) define(w http.ResponseWriter, req *http.Request) {
+	word := req.FormValue("word")
+	if word == "" {
+		http.Error(w, "no word given", http.StatusBadRequest)
+		return
+	}
+	def, ok := s.ga.Definitions(req.FormValue("list")).Define(word)
+	w.Header().Set("Content-Type", "application/json")
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+	}
+	json.NewEncoder(w).Encode(msg.DefinitionData{Word: word, Definition: def, Found: ok})
+}
@@ -1,8 +1,14 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/gorilla/websocket"
 
@@ -15,24 +21,36 @@ var upgrader = websocket.Upgrader{
 }
 
 type Server struct {
-	ga       *game.GameAssigner
-	serveMux *http.ServeMux
+	ga         *game.GameAssigner
+	house      *game.HouseRules
+	adminToken string
+	serveMux   *http.ServeMux
+	reloading  int32 // Set while the word lists are being reloaded.
+	bots       int32 // Number of bots started, to name them.
 }
 
-func NewServer() *Server {
-	ga := game.NewGameAssigner()
+func NewServer(dicts map[string]game.Dictionary, defs map[string]game.Definitions, defaultList string,
+	house *game.HouseRules, adminToken string) *Server {
+	ga := game.NewGameAssigner(dicts, defs, defaultList, house)
 	serveMux := http.NewServeMux()
 
 	s := &Server{
-		ga:       ga,
-		serveMux: serveMux,
+		ga:         ga,
+		house:      house,
+		adminToken: adminToken,
+		serveMux:   serveMux,
 	}
+	ga.StartBot = s.startBot
+	ga.Analyze = analyze
 	fileserver := http.FileServer(http.Dir("public"))
 	redirect := http.RedirectHandler("public/game.html", http.StatusFound)
 	serveMux.Handle("/", http.StripPrefix("/public/", redirect))
 	serveMux.Handle("/public/", http.StripPrefix("/public/", fileserver))
 	serveMux.HandleFunc("/connect", s.newConnection)
-	serveMux.HandleFunc("/game", s.newGame)
+	serveMux.HandleFunc("/admin/words", s.adminWords)
+	serveMux.HandleFunc("/admin/reload", s.adminReload)
+	serveMux.HandleFunc("/define", s.define)
+	serveMux.HandleFunc("/anagrams", s.anagrams)
 
 	return s
 }
@@ -47,10 +65,130 @@ func (s *Server) newConnection(w http.ResponseWriter, req *http.Request) {
 	s.ga.StartNewClient(conn)
 }
 
+// wordListFiles holds extra word lists to load, by name, from flags of the form NAME=FILE.
+type wordListFiles map[string]string
+
+func (f wordListFiles) String() string {
+	var lists []string
+	for name, file := range f {
+		lists = append(lists, name+"="+file)
+	}
+	sort.Strings(lists)
+	return strings.Join(lists, ",")
+}
+
+func (f wordListFiles) Set(s string) error {
+	i := strings.Index(s, "=")
+	if i <= 0 || i == len(s)-1 {
+		return fmt.Errorf("%q is not of the form NAME=FILE", s)
+	}
+	f[s[:i]] = s[i+1:]
+	return nil
+}
+
+var (
+	wordList   = flag.String("words", game.DefaultWordList, "name of the word list used unless players ask for another")
+	wordsFile  = flag.String("words-file", "", "word list or compiled dictionary to use as the default, named \"custom\"")
+	extraWords = wordListFiles{}
+	extraDefs  = wordListFiles{}
+	houseRules = flag.String("house-rules", "", "file holding the allow and ban lists, which is created if missing; empty keeps them in memory only")
+	adminToken = flag.String("admin-token", "", "token required by the admin endpoints, which are disabled without one")
+	hints      = flag.Bool("hints", false, "let players ask for hints during a round")
+	hintLimit  = flag.Int("hint-limit", 3, "most hints each player may take per round, or 0 for no limit")
+	hintWait   = flag.Duration("hint-cooldown", 30*time.Second, "how long a player must wait between hints")
+	hintCost   = flag.Int("hint-cost", 5, "points each hint costs at the end of the round")
+	repairs    = flag.Bool("repairs", true, "let players ask for their board to be repaired; turn off for competitive games")
+)
+
+func init() {
+	flag.Var(extraWords, "add-words", "extra word list players may ask for, as NAME=FILE; may be repeated")
+	flag.Var(extraDefs, "add-definitions", "definitions for the word list NAME, as NAME=FILE; may be repeated")
+}
+
+// loadDictionaries returns every built in word list and every list chosen by flags, by
+// name, along with the name of the default list.
+func loadDictionaries() (map[string]game.Dictionary, string, error) {
+	dicts, err := game.LoadWordLists()
+	if err != nil {
+		return nil, "", err
+	}
+	defaultList := *wordList
+	if *wordsFile != "" {
+		extraWords["custom"] = *wordsFile
+		defaultList = "custom"
+	}
+	for name, file := range extraWords {
+		if dicts[name], err = game.LoadDictionaryFile(file); err != nil {
+			return nil, "", err
+		}
+	}
+	if dicts[defaultList] == nil {
+		return nil, "", fmt.Errorf("no word list named %q", defaultList)
+	}
+	for name, lang := range game.LanguagePacks {
+		if lang.WordList != "" && dicts[lang.WordList] == nil {
+			log.Printf("Language %v is unavailable without -add-words %v=FILE", name, lang.WordList)
+		}
+	}
+	return dicts, defaultList, nil
+}
+
+// loadDefinitions returns the definitions of each word list, by the list's name, from any
+// files next to the word list files and any files chosen by flags.
+func loadDefinitions() (map[string]game.Definitions, error) {
+	files := make(map[string]string)
+	for name, file := range extraWords {
+		if defsFile := game.DefinitionsFile(file); fileExists(defsFile) {
+			files[name] = defsFile
+		}
+	}
+	for name, file := range extraDefs {
+		files[name] = file
+	}
+	defs := make(map[string]game.Definitions)
+	for name, file := range files {
+		d, err := game.LoadDefinitions(file)
+		if err != nil {
+			return nil, err
+		}
+		log.Println("Loaded", len(d), "definitions for", name, "from", file)
+		defs[name] = d
+	}
+	return defs, nil
+}
+
+func fileExists(filename string) bool {
+	_, err := os.Stat(filename)
+	return err == nil
+}
+
 func main() {
-	server := NewServer()
+	flag.Parse()
+	dicts, defaultList, err := loadDictionaries()
+	if err != nil {
+		log.Fatal("Could not load dictionary: ", err)
+	}
+	defs, err := loadDefinitions()
+	if err != nil {
+		log.Fatal("Could not load definitions: ", err)
+	}
+	house, err := game.LoadHouseRules(*houseRules)
+	if err != nil {
+		log.Fatal("Could not load house rules: ", err)
+	}
+	if *houseRules == "" {
+		log.Println("House rules will be lost on restart; set -house-rules to save them")
+	}
+	server := NewServer(dicts, defs, defaultList, house, *adminToken)
+	server.ga.SetHintRules(game.HintRules{
+		Allowed:  *hints,
+		Limit:    *hintLimit,
+		Cooldown: *hintWait,
+		Cost:     *hintCost,
+	})
+	server.ga.SetRepairs(*repairs)
 	go server.ga.Run()
-	game.InitDictionary()
+	go server.reloadOnHangup()
 
 	const addr = ":8888"
 	s := &http.Server{
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"log"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+)
+
+// reload loads the word lists and definitions again in the background, and swaps them in for
+// new rounds once they are all loaded.  Returns false if a reload is already running.
+func (s *Server) reload() bool {
+	if !atomic.CompareAndSwapInt32(&s.reloading, 0, 1) {
+		return false
+	}
+	go func() {
+		defer atomic.StoreInt32(&s.reloading, 0)
+		log.Println("Reloading word lists")
+		dicts, _, err := loadDictionaries()
+		if err != nil {
+			log.Println("Could not reload dictionary:", err)
+			return
+		}
+		defs, err := loadDefinitions()
+		if err != nil {
+			log.Println("Could not reload definitions:", err)
+			return
+		}
+		if err := s.ga.ReloadDictionaries(dicts, defs); err != nil {
+			log.Println("Could not reload word lists:", err)
+		}
+	}()
+	return true
+}
+
+// reloadOnHangup reloads the word lists whenever the server is sent SIGHUP.
+func (s *Server) reloadOnHangup() {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGHUP)
+	for range c {
+		if !s.reload() {
+			log.Println("Ignoring SIGHUP: already reloading word lists")
+		}
+	}
+}
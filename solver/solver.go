@@ -0,0 +1,445 @@
+// Package solver searches for a single connected crossword grid which uses every tile of a
+// hand, as a player must build to win a round.
+package solver
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kathrelkeld/speed-scrabble/game"
+)
+
+var (
+	// ErrNoGrid is returned when the search has tried every grid it can build.
+	ErrNoGrid = errors.New("no grid uses every tile")
+	// ErrTimeout is returned when the time budget runs out before a grid is found.
+	ErrTimeout = errors.New("no grid found within the time budget")
+)
+
+// Directions in which words are laid.
+var (
+	across = game.Vec{X: 1, Y: 0}
+	down   = game.Vec{X: 0, Y: 1}
+)
+
+// Solve searches for a board which uses every one of the given tiles in a single connected
+// grid of words, and which wins under cfg: words are checked against cfg.Dict, and the grid
+// fits within cfg.BoardSize unless it is zero.  It gives up once budget has passed.
+//
+// Each word after the first crosses exactly one tile already on the board and touches no
+// others, so some grids, such as those with words laid side by side, are never tried.
+func Solve(tiles []game.Tile, cfg game.Config, budget time.Duration) (game.Board, error) {
+	if len(tiles) == 0 {
+		return game.Board{}, nil
+	}
+	s := newSearch(tiles, cfg, time.Now().Add(budget))
+	s.findWords(true)
+
+	for _, c := range s.candidates(-1) {
+		if !s.fits(game.Vec{}, across, c) {
+			continue
+		}
+		s.place(game.Vec{}, across, c)
+		if s.extend() {
+			return s.board(), nil
+		}
+		s.unplace(game.Vec{}, across, c)
+		if s.timedOut() {
+			break
+		}
+	}
+	if s.timedOut() {
+		return nil, ErrTimeout
+	}
+	return nil, ErrNoGrid
+}
+
+// A Word can be made from the tiles of a hand.
+type Word struct {
+	Text string
+	Pts  int // The points of its tiles.
+}
+
+// Words returns every word in cfg.Dict which can be made from the given tiles, at least
+// cfg.Words.MinLength tiles long, with those worth the most points first.
+func Words(tiles []game.Tile, cfg game.Config) []Word {
+	s := newSearch(tiles, cfg, time.Time{})
+	s.findWords(false)
+	words := make([]Word, len(s.pool))
+	for i, w := range s.pool {
+		words[i] = Word{w.text, w.pts}
+	}
+	return words
+}
+
+// newSearch returns a search of the given tiles with nothing yet placed, which gives up at
+// deadline unless it is zero.
+func newSearch(tiles []game.Tile, cfg game.Config, deadline time.Time) *search {
+	s := &search{
+		cfg:      cfg,
+		deadline: deadline,
+		tiles:    tiles,
+		grid:     make(map[game.Vec]int),
+		left:     len(tiles),
+		words:    make(map[string]bool),
+		seen:     make(map[string]bool),
+	}
+	index := make(map[string]int)
+	for _, t := range tiles {
+		if _, ok := index[t.Value]; !ok {
+			index[t.Value] = 0
+			s.values = append(s.values, t.Value)
+		}
+	}
+	// Longer values come first, so that words are spelt with multi-letter tiles where
+	// possible, as they must be.
+	sort.Slice(s.values, func(i, j int) bool {
+		if len(s.values[i]) != len(s.values[j]) {
+			return len(s.values[i]) > len(s.values[j])
+		}
+		return s.values[i] < s.values[j]
+	})
+	s.points = make([]int, len(s.values))
+	s.hand = make([][]game.Tile, len(s.values))
+	for i, v := range s.values {
+		index[v] = i
+	}
+	for _, t := range tiles {
+		i := index[t.Value]
+		s.points[i] = t.Points
+		s.hand[i] = append(s.hand[i], t)
+	}
+	return s
+}
+
+// A search holds the grid built so far and the tiles left to place.  Tile values are
+// referred to by their index in values.
+type search struct {
+	cfg      game.Config
+	deadline time.Time   // Zero for no deadline.
+	expired  bool        // Set once the deadline has passed.
+	tiles    []game.Tile // Every tile of the hand.
+	values   []string
+	points   []int // The points of each value.
+	// The tiles of each value.  Placing a tile shortens its slice, so the tiles on the
+	// board are kept just past the end.
+	hand  [][]game.Tile
+	pool  []*word          // Every word which could ever be laid.
+	grid  map[game.Vec]int // The value at each coordinate of the board.
+	left  int              // Number of tiles not yet placed.
+	words map[string]bool  // Words on the grid, so that none is repeated.
+	seen  map[string]bool  // Grids already searched from.
+	need  []int            // Scratch space for counting the tiles a word needs.
+}
+
+// A word is in the dictionary, and can be made from the tiles of the hand along with at
+// most one more, which must come from the board.
+type word struct {
+	text   string
+	values []int // The value of each tile in the word.
+	pts    int   // The points of every tile in the word.
+}
+
+// A candidate is a word which can be laid with the tiles in hand, crossing at most one tile
+// already on the board.
+type candidate struct {
+	*word
+	anchor int // Index of the tile already on the board, or -1.
+}
+
+// timedOut returns whether the time budget has run out.
+func (s *search) timedOut() bool {
+	if !s.expired && !s.deadline.IsZero() && time.Now().After(s.deadline) {
+		s.expired = true
+	}
+	return s.expired
+}
+
+// findWords fills the pool with every word which might be laid during the search, so that
+// the dictionary is only searched once.  Words may take one tile more than the hand holds,
+// to be crossed on the board, if extra is set.
+func (s *search) findWords(extra bool) {
+	s.need = make([]int, len(s.values))
+	s.cfg.Dict.Each(func(w string) bool {
+		if values, ok := s.spell(w, nil, extra); ok && len(values) >= 2 && len(values) >= s.cfg.Words.MinLength && !s.misspelt(values) {
+			pts := 0
+			for _, v := range values {
+				pts += s.points[v]
+			}
+			s.pool = append(s.pool, &word{w, values, pts})
+		}
+		return !s.timedOut()
+	})
+
+	// Trying words worth the most points first places hard letters while there are still
+	// others to combine them with, as well as preferring long words.
+	sort.Slice(s.pool, func(i, j int) bool {
+		if s.pool[i].pts != s.pool[j].pts {
+			return s.pool[i].pts > s.pool[j].pts
+		}
+		return s.pool[i].text < s.pool[j].text
+	})
+}
+
+// spell returns the values of the tiles which spell w after those already in values, using
+// tiles from the hand, and one more tile if extra is set.
+func (s *search) spell(w string, values []int, extra bool) ([]int, bool) {
+	if w == "" {
+		return values, true
+	}
+	for v, value := range s.values {
+		if !strings.HasPrefix(w, value) {
+			continue
+		}
+		fromHand := s.need[v] < len(s.hand[v])
+		if !fromHand && !extra {
+			continue
+		}
+		s.need[v]++
+		result, ok := s.spell(w[len(value):], append(values, v), extra && fromHand)
+		s.need[v]--
+		if ok {
+			return result, true
+		}
+	}
+	return nil, false
+}
+
+// misspelt returns whether the given values spell out one of the language's multi-letter
+// tiles with separate tiles, as a C and an H do for CH, which the game rejects.  Values are
+// tried longest first, so this only happens when the hand lacks the multi-letter tile.
+func (s *search) misspelt(values []int) bool {
+	letters := make([]string, len(values))
+	for i, v := range values {
+		letters[i] = s.values[v]
+	}
+	_, bad := s.cfg.Language.Misspelling(letters)
+	return bad
+}
+
+// fromHand returns whether the tiles of w, other than the one at skip, are all in hand.
+func (s *search) fromHand(w *word, skip int) bool {
+	for i := range s.need {
+		s.need[i] = 0
+	}
+	for k, v := range w.values {
+		if k == skip {
+			continue
+		}
+		s.need[v]++
+		if s.need[v] > len(s.hand[v]) {
+			return false
+		}
+	}
+	return true
+}
+
+// candidates returns every word which can be made from tiles in hand, along with a tile of
+// the given value already on the board, unless it is -1, in the order of the pool.  As
+// every candidate crosses the same value, those using the most points from the hand come
+// first.
+func (s *search) candidates(anchor int) []candidate {
+	var result []candidate
+	for _, w := range s.pool {
+		if s.cfg.Words.NoRepeats && s.words[w.text] {
+			continue
+		}
+		if anchor < 0 {
+			if s.fromHand(w, -1) {
+				result = append(result, candidate{w, -1})
+			}
+			continue
+		}
+		for k, v := range w.values {
+			if v == anchor && s.fromHand(w, k) {
+				result = append(result, candidate{w, k})
+			}
+		}
+	}
+	return result
+}
+
+// at returns the coordinates of the kth tile of the candidate when laid in direction d with
+// its anchor at a.  A candidate without an anchor starts at a.
+func (c candidate) at(a, d game.Vec, k int) game.Vec {
+	if c.anchor >= 0 {
+		k -= c.anchor
+	}
+	return game.Vec{X: a.X + d.X*k, Y: a.Y + d.Y*k}
+}
+
+// occupied returns whether there is a tile at v.
+func (s *search) occupied(v game.Vec) bool {
+	_, ok := s.grid[v]
+	return ok
+}
+
+// fits returns whether the candidate can be laid in direction d with its anchor at a,
+// touching no tile on the board other than its anchor, and keeping within the board size.
+func (s *search) fits(a, d game.Vec, c candidate) bool {
+	p := game.Vec{X: d.Y, Y: d.X}
+	if s.occupied(c.at(a, d, -1)) || s.occupied(c.at(a, d, len(c.values))) {
+		return false
+	}
+	for k := range c.values {
+		if k == c.anchor {
+			continue
+		}
+		v := c.at(a, d, k)
+		before := game.Vec{X: v.X - p.X, Y: v.Y - p.Y}
+		after := game.Vec{X: v.X + p.X, Y: v.Y + p.Y}
+		if s.occupied(v) || s.occupied(before) || s.occupied(after) {
+			return false
+		}
+	}
+	if size := s.cfg.BoardSize; size.X > 0 && size.Y > 0 {
+		start, end := c.at(a, d, 0), c.at(a, d, len(c.values)-1)
+		lo, hi := start, end
+		if len(s.grid) > 0 {
+			lo, hi = s.bounds()
+			lo = game.Vec{X: min(lo.X, start.X), Y: min(lo.Y, start.Y)}
+			hi = game.Vec{X: max(hi.X, end.X), Y: max(hi.Y, end.Y)}
+		}
+		if hi.X-lo.X >= size.X || hi.Y-lo.Y >= size.Y {
+			return false
+		}
+	}
+	return true
+}
+
+// place lays the candidate in direction d with its anchor at a, taking its tiles from hand.
+func (s *search) place(a, d game.Vec, c candidate) {
+	for k, v := range c.values {
+		if k != c.anchor {
+			s.hand[v] = s.hand[v][:len(s.hand[v])-1]
+			s.grid[c.at(a, d, k)] = v
+			s.left--
+		}
+	}
+	s.words[c.text] = true
+}
+
+// unplace undoes place, returning the candidate's tiles to hand.
+func (s *search) unplace(a, d game.Vec, c candidate) {
+	for k, v := range c.values {
+		if k != c.anchor {
+			s.hand[v] = s.hand[v][:len(s.hand[v])+1]
+			delete(s.grid, c.at(a, d, k))
+			s.left++
+		}
+	}
+	delete(s.words, c.text)
+}
+
+// extend lays words across the board until every tile is placed and the board wins, and
+// returns whether it did.  The board is left as it was if not.
+func (s *search) extend() bool {
+	if s.left == 0 {
+		return s.board().Score(s.tiles, s.cfg).Win
+	}
+	key := s.key()
+	if s.seen[key] || s.timedOut() {
+		return false
+	}
+	s.seen[key] = true
+
+	// Words are only found for each value on the board once they are needed, as the first
+	// tried is often enough.
+	cands := make(map[int][]candidate)
+	for _, a := range s.sortedVecs() {
+		value := s.grid[a]
+		if _, ok := cands[value]; !ok {
+			cands[value] = s.candidates(value)
+		}
+		for _, d := range []game.Vec{across, down} {
+			for _, c := range cands[value] {
+				if !s.fits(a, d, c) {
+					continue
+				}
+				s.place(a, d, c)
+				if s.extend() {
+					return true
+				}
+				s.unplace(a, d, c)
+				if s.timedOut() {
+					return false
+				}
+			}
+		}
+	}
+	return false
+}
+
+// sortedVecs returns the coordinates of every tile on the board, in reading order.
+func (s *search) sortedVecs() []game.Vec {
+	var vs []game.Vec
+	for v := range s.grid {
+		vs = append(vs, v)
+	}
+	sort.Slice(vs, func(i, j int) bool {
+		if vs[i].Y != vs[j].Y {
+			return vs[i].Y < vs[j].Y
+		}
+		return vs[i].X < vs[j].X
+	})
+	return vs
+}
+
+// key identifies the board by the value at each of its coordinates.
+func (s *search) key() string {
+	var b []byte
+	for _, v := range s.sortedVecs() {
+		b = append(b, v.String()...)
+		b = append(b, s.values[s.grid[v]]...)
+		b = append(b, ';')
+	}
+	return string(b)
+}
+
+// bounds returns the smallest and largest coordinates of any tile on the board.
+func (s *search) bounds() (lo, hi game.Vec) {
+	first := true
+	for v := range s.grid {
+		if first {
+			lo, hi = v, v
+			first = false
+			continue
+		}
+		lo = game.Vec{X: min(lo.X, v.X), Y: min(lo.Y, v.Y)}
+		hi = game.Vec{X: max(hi.X, v.X), Y: max(hi.Y, v.Y)}
+	}
+	return lo, hi
+}
+
+// board returns the tiles laid so far as a Board just large enough to hold them.
+func (s *search) board() game.Board {
+	lo, hi := s.bounds()
+	b := make(game.Board, hi.Y-lo.Y+1)
+	for j := range b {
+		b[j] = make([]*game.Tile, hi.X-lo.X+1)
+	}
+	used := make([]int, len(s.values))
+	for _, v := range s.sortedVecs() {
+		i := s.grid[v]
+		t := s.hand[i][:len(s.hand[i])+used[i]+1][len(s.hand[i])+used[i]]
+		used[i]++
+		b[v.Y-lo.Y][v.X-lo.X] = &t
+	}
+	return b
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
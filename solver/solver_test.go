@@ -0,0 +1,142 @@
+package solver
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/kathrelkeld/speed-scrabble/game"
+)
+
+var makeTiles = game.LanguagePacks["english"].MakeTiles
+
+// randomHand returns n tiles drawn from a shuffled English bag.
+func randomHand(r *rand.Rand, n int) []game.Tile {
+	var letters []string
+	for l, cnt := range game.LanguagePacks["english"].Tiles {
+		for i := 0; i < cnt; i++ {
+			letters = append(letters, l)
+		}
+	}
+	// Sort before shuffling so that the hand depends only on the seed.
+	sort.Strings(letters)
+	r.Shuffle(len(letters), func(i, j int) { letters[i], letters[j] = letters[j], letters[i] })
+	return makeTiles(letters[:n]...)
+}
+
+func checkSolution(t *testing.T, name string, tiles []game.Tile, cfg game.Config, budget time.Duration) {
+	b, err := Solve(tiles, cfg, budget)
+	if err != nil {
+		t.Errorf("%v: %v", name, err)
+		return
+	}
+	if s := b.Score(tiles, cfg); !s.Win {
+		t.Errorf("%v: solution does not win:\n%v%v", name, b, s)
+	}
+	if size := cfg.BoardSize; len(b) > size.Y || len(b[0]) > size.X {
+		t.Errorf("%v: solution larger than %v:\n%v", name, size, b)
+	}
+}
+
+func TestSolveTestDict(t *testing.T) {
+	dict, err := game.LoadDictionaryFile("../game/test_data/dict.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := game.DefaultConfig(dict)
+	for _, hand := range [][]string{
+		{"Q", "I"},
+		{"C", "A", "T", "S"},
+		{"C", "A", "T", "S", "I"},
+		{"F", "A", "N", "C", "T", "O", "O"},
+	} {
+		checkSolution(t, hand[0]+"...", makeTiles(hand...), cfg, time.Second)
+	}
+
+	for _, hand := range [][]string{
+		{"Q"},
+		{"Q", "Q"},
+		{"C", "A", "T", "Z"},
+	} {
+		if b, err := Solve(makeTiles(hand...), cfg, time.Second); err != ErrNoGrid {
+			t.Errorf("%v: Got %v, %v; Expected %v", hand, b, err, ErrNoGrid)
+		}
+	}
+
+	// Without room for both words, there is no grid.
+	cfg.BoardSize = game.Vec{X: 4, Y: 1}
+	if _, err := Solve(makeTiles("C", "A", "T", "S", "I"), cfg, time.Second); err != ErrNoGrid {
+		t.Errorf("Solve on small board: Got %v; Expected %v", err, ErrNoGrid)
+	}
+}
+
+func TestSolveMultiLetterTiles(t *testing.T) {
+	cfg := game.DefaultConfig(game.Dict{"CHE": {}, "ECHE": {}})
+	cfg.Language = game.LanguagePacks["spanish"]
+	spanish := func(letters ...string) []game.Tile {
+		var tiles []game.Tile
+		for i, l := range letters {
+			tiles = append(tiles, game.Tile{Value: l, Points: cfg.Language.Points[l], ID: i})
+		}
+		return tiles
+	}
+	checkSolution(t, "CH", spanish("E", "CH", "E"), cfg, time.Second)
+
+	// CHE must be spelt with the CH tile.
+	if b, err := Solve(spanish("C", "H", "E"), cfg, time.Second); err != ErrNoGrid {
+		t.Errorf("Solve without CH tile: Got %v, %v; Expected %v", b, err, ErrNoGrid)
+	}
+}
+
+func TestSolveSOWPODS(t *testing.T) {
+	dict, err := game.LoadWordList("sowpods")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := game.DefaultConfig(dict)
+	checkSolution(t, "fixed hand", makeTiles("Q", "U", "I", "Z", "J", "A", "X", "E", "S", "T", "O", "N"), cfg, 10*time.Second)
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 10; i++ {
+		checkSolution(t, "random hand", randomHand(r, 21), cfg, 10*time.Second)
+	}
+
+	if _, err := Solve(randomHand(r, 21), cfg, 0); err != ErrTimeout {
+		t.Errorf("Solve without time: Got %v; Expected %v", err, ErrTimeout)
+	}
+}
+
+func TestWords(t *testing.T) {
+	dict, err := game.LoadDictionaryFile("../game/test_data/dict.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := game.DefaultConfig(dict)
+	got := Words(makeTiles("C", "A", "T", "S", "Q"), cfg)
+	want := []Word{{"CATS", 6}, {"ACT", 5}, {"CAT", 5}, {"AS", 2}, {"AT", 2}}
+	if len(got) != len(want) {
+		t.Fatalf("got words %v; want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got words %v; want %v", got, want)
+			break
+		}
+	}
+
+	cfg.Words.MinLength = 3
+	if got := Words(makeTiles("A", "S"), cfg); len(got) != 0 {
+		t.Errorf("got words %v shorter than the minimum length", got)
+	}
+
+	// Words must be spelt with multi-letter tiles, so a C and an H do not make CH.
+	cfg = game.DefaultConfig(game.Dict{"CHE": {}, "HE": {}})
+	cfg.Language = game.LanguagePacks["spanish"]
+	if got := Words(makeTiles("C", "H", "E"), cfg); len(got) != 1 || got[0].Text != "HE" {
+		t.Errorf("got words %v from C, H and E; want only HE", got)
+	}
+	if got := Words(makeTiles("CH", "E"), cfg); len(got) != 1 || got[0].Text != "CHE" {
+		t.Errorf("got words %v from CH and E; want only CHE", got)
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"unicode/utf8"
 )
 
 func (mgr *GameManager) drawTile(t *Tile) {
@@ -14,7 +15,12 @@ func (mgr *GameManager) drawTile(t *Tile) {
 	}
 	mgr.ctx.FillRect(t.Loc, mgr.tileSize)
 
-	// Draw letter;
+	// Draw letter, smaller if the tile has several.
+	size := mgr.tileSize.X
+	if n := utf8.RuneCountInString(t.Value); n > 1 {
+		size = size * 5 / (4 * n)
+	}
+	mgr.ctx.Set("font", fmt.Sprintf("%v", size)+"px Arial")
 	switch t.State {
 	case TileStateUnused:
 		mgr.ctx.Set("fillStyle", "grey")
@@ -51,6 +57,55 @@ func (mgr *GameManager) drawBadWords() {
 	mgr.ctx.Stroke()
 }
 
+// drawIslands labels each separately scored island with the points it would get alone, above
+// its first tile.
+func (mgr *GameManager) drawIslands() {
+	if len(mgr.islands) < 2 {
+		return
+	}
+	mgr.ctx.Set("textAlign", "left")
+	mgr.ctx.Set("textBaseline", "bottom")
+	mgr.ctx.Set("font", fmt.Sprintf("%v", mgr.tileSize.X/3)+"px Arial")
+	mgr.ctx.Set("fillStyle", "green")
+	for _, island := range mgr.islands {
+		l := mgr.board.canvasStart(island.Start)
+		mgr.ctx.FillText(fmt.Sprintf("%v pts", island.Pts), l)
+	}
+}
+
+// drawHint fills in the empty cells of the last hint, with the letter suggested for each,
+// and outlines the tile it suggests moving, if any.
+func (mgr *GameManager) drawHint() {
+	if mgr.hint == nil {
+		return
+	}
+	mgr.ctx.Set("globalAlpha", 0.5)
+	if m := mgr.hint.Moved; m != nil {
+		l := mgr.board.canvasStart(Vec{m.X, m.Y})
+		mgr.ctx.BeginPath()
+		mgr.ctx.Set("strokeStyle", "orange")
+		mgr.ctx.Set("lineWidth", 4)
+		mgr.drawRectBetween(l, Add(l, mgr.tileSize), 2)
+		mgr.ctx.ClosePath()
+		mgr.ctx.Stroke()
+	}
+	mgr.ctx.Set("textAlign", "center")
+	mgr.ctx.Set("textBaseline", "middle")
+	mgr.ctx.Set("font", fmt.Sprintf("%v", mgr.tileSize.X/2)+"px Arial")
+	for _, t := range mgr.hint.Tiles {
+		idx := Vec{t.X, t.Y}
+		if mgr.board.Get(idx) != nil {
+			continue
+		}
+		l := mgr.board.canvasStart(idx)
+		mgr.ctx.Set("fillStyle", "lightgreen")
+		mgr.ctx.FillRect(l, mgr.tileSize)
+		mgr.ctx.Set("fillStyle", "black")
+		mgr.ctx.FillText(t.Value, Add(l, ScaleDown(mgr.tileSize, 2)))
+	}
+	mgr.ctx.Set("globalAlpha", 1.0)
+}
+
 func (mgr *GameManager) drawWordDir() {
 	if !mgr.highlight.active {
 		return
@@ -84,6 +139,15 @@ func (mgr *GameManager) drawHighlight() {
 	mgr.ctx.ClosePath()
 	mgr.ctx.Stroke()
 
+	// Show any letters held back until the next one is typed.
+	if mgr.highlight.typed != "" {
+		mgr.ctx.Set("fillStyle", "grey")
+		mgr.ctx.Set("textAlign", "center")
+		mgr.ctx.Set("textBaseline", "middle")
+		mgr.ctx.Set("font", fmt.Sprintf("%v", mgr.tileSize.X/2)+"px Arial")
+		mgr.ctx.FillText(mgr.highlight.typed+"…", Add(l, ScaleDown(mgr.tileSize, 2)))
+	}
+
 	mgr.ctx.Set("globalAlpha", 1.0)
 }
 
@@ -105,7 +169,34 @@ func (mgr *GameManager) drawLineBetween(a, b Vec) {
 	mgr.ctx.LineTo(b)
 }
 
+var premiumColors = map[string]string{
+	"DL": "lightblue",
+	"TL": "royalblue",
+	"DW": "pink",
+	"TW": "tomato",
+}
+
+// drawPremiums fills in and labels any premium squares on the given grid.
+func (mgr *GameManager) drawPremiums(g *Grid, tileSize Vec) {
+	mgr.ctx.Set("textAlign", "center")
+	mgr.ctx.Set("textBaseline", "middle")
+	mgr.ctx.Set("font", fmt.Sprintf("%v", tileSize.X/3)+"px Arial")
+	for idx, kind := range mgr.premiums {
+		if !g.InCoords(idx) {
+			continue
+		}
+		l := g.canvasStart(idx)
+		mgr.ctx.Set("fillStyle", premiumColors[kind])
+		mgr.ctx.FillRect(l, tileSize)
+		mgr.ctx.Set("fillStyle", "black")
+		mgr.ctx.FillText(kind, Add(l, ScaleDown(tileSize, 2)))
+	}
+}
+
 func (mgr *GameManager) drawGrid(g *Grid, tileSize Vec) {
+	if g.Zone == ZoneBoard {
+		mgr.drawPremiums(g, tileSize)
+	}
 	mgr.ctx.BeginPath()
 	//mgr.ctx.Set("globalAlpha", 1.0)
 	mgr.ctx.Set("lineWidth", 2)
@@ -138,6 +229,8 @@ func (mgr *GameManager) draw() {
 	mgr.drawTiles()
 
 	mgr.drawBadWords()
+	mgr.drawIslands()
+	mgr.drawHint()
 
 	mgr.drawHighlight()
 	if mgr.move.active && mgr.move.onTile {
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"math/rand"
 	"syscall/js"
+
+	"github.com/kathrelkeld/speed-scrabble/msg"
 )
 
 const (
@@ -21,12 +23,16 @@ type GameManager struct {
 	tiles     []*Tile // All given tiles, regardless of their location.
 	tileSize  Vec     // The canvas size of a single tile.
 	badWords  []Word
-	move      *Move      // Current move action.
-	highlight *Highlight // Current board highlight.
+	islands   []Island       // Separately scored islands of the board, if more than one counts.
+	premiums  map[Vec]string // Premium square kinds on the board, e.g. "DW".
+	hint      *msg.HintData  // The last hint given, shown until the board is next sent.
+	move      *Move          // Current move action.
+	highlight *Highlight     // Current board highlight.
 	listens   Listeners
 	ctx       Context
 	canvas    Canvas
 	socket    js.Value
+	protocol  int // How boards are sent to the server, e.g. msg.ProtocolSparse.
 }
 
 // NewGameManager resets the global variable mgr with a new state for a new game.
@@ -70,6 +76,7 @@ func (mgr *GameManager) Reset() {
 	mgr.badWords = next.badWords
 	mgr.move = next.move
 	mgr.highlight = next.highlight
+	mgr.hint = next.hint
 }
 
 // Tile represents a single tile.  Marshalling must match the slimmer version of Tile
@@ -79,6 +86,8 @@ type Tile struct {
 	Value string
 	// Points are the number of points this tile represents.
 	Points int
+	// ID identifies this tile to the server.
+	ID int
 	// Zone indicates where this tile is, e.g. on the board or moving.
 	Zone int `json:"-"`
 	// Idx is where this tile is on a Grid, assuming it is on a Grid.
@@ -89,6 +98,8 @@ type Tile struct {
 	MoveOffset Vec `json:"-"`
 	// State is whether this tile should be drawn as an invalid or unused tile.
 	State int `json:"-"`
+	// Reason explains the State of this tile, as given by the last score.
+	Reason string `json:"-"`
 	// mgr references the parent GameManager which added this tile.
 	mgr *GameManager
 }
@@ -170,11 +181,22 @@ func (mgr *GameManager) markInvalidAndUnusedTiles(invalid, unused []Vec, badWord
 	mgr.badWords = badWords
 }
 
+// setTileReasons records the reason given for each tile on the board.
+func (mgr *GameManager) setTileReasons(reasons []TileReason) {
+	for _, r := range reasons {
+		if t := mgr.board.Get(r.Loc); t != nil {
+			t.Reason = r.Reason
+		}
+	}
+}
+
 // markAllTilesValid undoes markInvalidTiles.
 func (mgr *GameManager) unmarkAllTiles() {
 	mgr.badWords = []Word{}
+	mgr.islands = nil
 	for _, t := range mgr.tiles {
 		t.State = TileStateValid
+		t.Reason = ""
 	}
 }
 
@@ -1,5 +1,9 @@
 package main
 
+import (
+	"github.com/kathrelkeld/speed-scrabble/msg"
+)
+
 type Grid struct {
 	// Grid is the slice of slices representation of the entire grid.
 	Grid [][]*Tile
@@ -63,6 +67,39 @@ func (g *Grid) coords(loc Vec) Vec {
 	}
 }
 
+// wordsThrough returns the words of two or more tiles which run across and down through idx.
+func (g *Grid) wordsThrough(idx Vec) []string {
+	var words []string
+	for _, d := range []Vec{{1, 0}, {0, 1}} {
+		start := idx
+		for prev := Sub(start, d); g.InCoords(prev) && g.Get(prev) != nil; prev = Sub(prev, d) {
+			start = prev
+		}
+		w, n := "", 0
+		for v := start; g.InCoords(v) && g.Get(v) != nil; v = Add(v, d) {
+			w += g.Get(v).Value
+			n++
+		}
+		if n > 1 {
+			words = append(words, w)
+		}
+	}
+	return words
+}
+
 func (g *Grid) canvasStart(idx Vec) Vec {
 	return Add(g.Loc, Mult(idx, g.mgr.tileSize))
 }
+
+// placedTiles returns every tile on this grid in the form sent with msg.ProtocolSparse.
+func (g *Grid) placedTiles() []msg.PlacedTile {
+	placed := []msg.PlacedTile{}
+	for j, row := range g.Grid {
+		for i, t := range row {
+			if t != nil {
+				placed = append(placed, msg.PlacedTile{X: i, Y: j, Value: t.Value, ID: t.ID})
+			}
+		}
+	}
+	return placed
+}
@@ -3,6 +3,8 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"strconv"
+	"syscall/js"
 
 	"github.com/kathrelkeld/speed-scrabble/msg"
 )
@@ -10,23 +12,52 @@ import (
 type TileSet map[Vec]*Tile
 
 type Word struct {
-	Start Vec
-	End   Vec
-	Value string
+	Start  Vec
+	End    Vec
+	Value  string
+	Reason string // Why this word is not valid, if it is not.
+}
+
+// TileReason explains why the tile at Loc was or was not counted.
+type TileReason struct {
+	Loc    Vec
+	Reason string
 }
 
 type Score struct {
-	Win         bool   // Whether the board ends the game or not.
-	Pts         int    // The numerical score (lower is better).
-	Valid       []Vec  // Tiles which were part of valid words.
-	Invalid     []Vec  // Tiles which were part of no valid words.
-	Unconnected []Vec  // Tiles not part of the best scoring component.
-	Words       []Word // Words found in the dictionary.
-	Nonwords    []Word // Words not found in the dictionary.
+	Win         bool         // Whether the board ends the game or not.
+	Pts         int          // The numerical score, as counted by Rule.
+	Rule        string       // The name of the scoring rule used.
+	WordList    string       // The name of the word list used.
+	DictVersion int          // The version of the word list used, which goes up with each reload.
+	Valid       []Vec        // Tiles which were part of valid words.
+	Invalid     []Vec        // Tiles which were part of no valid words.
+	Unconnected []Vec        // Tiles not part of the best scoring component.
+	Words       []Word       // Words found in the dictionary.
+	Nonwords    []Word       // Words not found in the dictionary.
+	Reasons     []TileReason // Why each tile was or was not counted.
+	Islands     []Island     // Each island counted, when every island is scored.
+}
+
+// An Island is a separately scored group of connected tiles.
+type Island struct {
+	Start Vec // Coordinates of the first tile of the island.
+	Pts   int // Points the rule gives the island, as if no other island were counted.
 }
 
 func (mgr *GameManager) joinGame() {
-	m, _ := msg.NewSocketData(msg.JoinGame, "NAME")
+	penalty, _ := strconv.Atoi(pageParam("island-penalty"))
+	m, _ := msg.NewSocketData(msg.JoinGame, msg.JoinGameData{
+		Name:          "NAME",
+		Protocol:      msg.ProtocolSparse,
+		WordList:      pageParam("words"),
+		Language:      pageParam("lang"),
+		Rule:          pageParam("rule"),
+		Layout:        pageParam("layout"),
+		Words:         pageParam("mode"),
+		Islands:       pageParam("islands") == "true",
+		IslandPenalty: penalty,
+	})
 	mgr.websocketSend(m)
 }
 
@@ -36,24 +67,61 @@ func (mgr *GameManager) requestNewTile() {
 
 func (mgr *GameManager) newGame() {
 	if mgr.state == StateNoGame {
-		m, _ := msg.NewSocketData(msg.JoinGame, "NAME")
-		mgr.websocketSend(m)
+		mgr.joinGame()
 	} else {
 		mgr.websocketSendEmpty(msg.RoundReady)
 	}
 }
 
-func (mgr *GameManager) verify() {
-	// TODO: send only tiles instead of entire board
-	m, _ := msg.NewSocketData(msg.Verify, mgr.board.Grid)
+// addBot asks for a computer player, of the level chosen on the page, to join the game.
+func (mgr *GameManager) addBot() {
+	level := js.Global().Get("document").Call("getElementById", "botLevel").Get("value").String()
+	m, _ := msg.NewSocketData(msg.AddBot, level)
+	mgr.websocketSend(m)
+}
+
+// removeBot asks for a computer player to leave the game.
+func (mgr *GameManager) removeBot() {
+	mgr.websocketSendEmpty(msg.RemoveBot)
+}
+
+// sendBoard sends the board as a message of the given type, in the negotiated protocol.
+func (mgr *GameManager) sendBoard(t msg.Type) {
+	var m []byte
+	if mgr.protocol == msg.ProtocolSparse {
+		m, _ = msg.NewSocketData(t, mgr.board.placedTiles())
+	} else {
+		m, _ = msg.NewSocketData(t, mgr.board.Grid)
+	}
 	mgr.websocketSend(m)
 }
 
+func (mgr *GameManager) verify() {
+	mgr.hint = nil
+	mgr.sendBoard(msg.Verify)
+}
+
+// requestHint asks for a word to add to the board as it is now.
+func (mgr *GameManager) requestHint() {
+	mgr.sendBoard(msg.Hint)
+}
+
 func (mgr *GameManager) handleSocketMsg(t msg.Type, data []byte) int {
 	switch t {
 	case msg.PlayerJoined:
+		// Servers which do not reply with a protocol only accept the full grid.
+		joined := msg.PlayerJoinedData{Protocol: msg.ProtocolGrid}
+		if err := json.Unmarshal(data, &joined); err != nil {
+			fmt.Println("Error reading player joined:", err)
+		}
+		mgr.protocol = joined.Protocol
 		mgr.websocketSendEmpty(msg.RoundReady)
 	case msg.Error:
+		// Errors about a board come with its score rather than any text.
+		var text string
+		if err := json.Unmarshal(data, &text); err == nil {
+			showError(text)
+		}
 	case msg.Start:
 		// TODO tie to actual game size
 		mgr.Reset()
@@ -70,6 +138,9 @@ func (mgr *GameManager) handleSocketMsg(t msg.Type, data []byte) int {
 			tile.sendToTray()
 		}
 		mgr.listens.NewGame()
+		clearMessages()
+		clearAnalysis()
+		mgr.showDisputes(nil)
 		mgr.state = StatePlaying
 		EnableGameButtons()
 		mgr.draw()
@@ -85,19 +156,19 @@ func (mgr *GameManager) handleSocketMsg(t msg.Type, data []byte) int {
 		tile.sendToTray()
 		mgr.draw()
 	case msg.Result:
-		var score Score
-		err := json.Unmarshal(data, &score)
+		var result msg.ResultData
+		err := json.Unmarshal(data, &result)
 		if err != nil {
-			fmt.Println("Error reading score:", err)
+			fmt.Println("Error reading result:", err)
 			return 1
 		}
 		mgr.listens.EndGame()
 		DisableGameButtons()
 		mgr.unhighlight()
-		mgr.markInvalidAndUnusedTiles(score.Invalid, score.Unconnected, score.Nonwords)
+		showResults(result)
 		mgr.draw()
 		mgr.state = StateGameOver
-	case msg.Invalid:
+	case msg.Score, msg.Invalid:
 		var score Score
 		err := json.Unmarshal(data, &score)
 		if err != nil {
@@ -105,10 +176,61 @@ func (mgr *GameManager) handleSocketMsg(t msg.Type, data []byte) int {
 			return 1
 		}
 		mgr.markInvalidAndUnusedTiles(score.Invalid, score.Unconnected, score.Nonwords)
+		mgr.setTileReasons(score.Reasons)
+		mgr.islands = score.Islands
+		mgr.showDisputes(score.Nonwords)
 		mgr.draw()
+	case msg.Define:
+		var d msg.DefinitionData
+		err := json.Unmarshal(data, &d)
+		if err != nil {
+			fmt.Println("Error reading definition:", err)
+			return 1
+		}
+		showDefinition(d)
+	case msg.Vote:
+		var v msg.VoteData
+		err := json.Unmarshal(data, &v)
+		if err != nil {
+			fmt.Println("Error reading vote:", err)
+			return 1
+		}
+		mgr.showVote(v)
+	case msg.VoteResult:
+		var r msg.VoteResultData
+		err := json.Unmarshal(data, &r)
+		if err != nil {
+			fmt.Println("Error reading vote result:", err)
+			return 1
+		}
+		showVoteResult(r)
+	case msg.Hint:
+		var h msg.HintData
+		err := json.Unmarshal(data, &h)
+		if err != nil {
+			fmt.Println("Error reading hint:", err)
+			return 1
+		}
+		mgr.hint = &h
+		mgr.draw()
+	case msg.Repair:
+		var r msg.RepairData
+		err := json.Unmarshal(data, &r)
+		if err != nil {
+			fmt.Println("Error reading repair:", err)
+			return 1
+		}
+		mgr.animateRepair(r.Moves)
+	case msg.Analysis:
+		var a msg.AnalysisData
+		err := json.Unmarshal(data, &a)
+		if err != nil {
+			fmt.Println("Error reading analysis:", err)
+			return 1
+		}
+		mgr.showAnalysis(a)
 	case msg.SendBoard:
-		m, _ := msg.NewSocketData(msg.SendBoard, mgr.board.Grid)
-		mgr.websocketSend(m)
+		mgr.sendBoard(msg.SendBoard)
 	case msg.GameInfo:
 		var s msg.GameInfoData
 		err := json.Unmarshal(data, &s)
@@ -116,7 +238,11 @@ func (mgr *GameManager) handleSocketMsg(t msg.Type, data []byte) int {
 			fmt.Println("Error reading game info:", err)
 			return 1
 		}
-		fmt.Println("Game:", s.GameName)
+		fmt.Println("Game:", s.GameName, "in", s.Language, "using word list", s.WordList)
+		mgr.premiums = make(map[Vec]string)
+		for _, p := range s.Premiums {
+			mgr.premiums[Vec{p.X, p.Y}] = p.Kind
+		}
 	}
 	return 0
 }
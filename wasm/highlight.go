@@ -1,9 +1,12 @@
 package main
 
+import "strings"
+
 type Highlight struct {
 	active bool
-	idx    Vec // Coordinates on the board where the highlight starts.
-	dir    Vec // Direction to advance when typing.
+	idx    Vec    // Coordinates on the board where the highlight starts.
+	dir    Vec    // Direction to advance when typing.
+	typed  string // Letters typed which may be the start of a multi-letter tile, e.g. the C of CH.
 }
 
 // moveHighlight moves the highlight in the given direction.
@@ -34,6 +37,54 @@ func (mgr *GameManager) findForHighlight(v string) {
 	}
 }
 
+// typeLetter places the tile for a letter typed at the highlight.  A letter which starts a
+// longer tile that could be placed, such as the C of CH, is held until the next letter shows
+// which tile was meant.
+func (mgr *GameManager) typeLetter(l string) {
+	typed := mgr.highlight.typed + l
+	mgr.highlight.typed = ""
+	longer, exact := mgr.typeableTiles(typed)
+	switch {
+	case longer:
+		mgr.highlight.typed = typed
+	case exact || typed == l:
+		mgr.findForHighlight(typed)
+	default:
+		// The held letters make no tile with this one, so place them on their own first.
+		mgr.findForHighlight(strings.TrimSuffix(typed, l))
+		mgr.typeLetter(l)
+	}
+}
+
+// typeableTiles returns whether any tile which could be placed at the highlight starts with
+// the given letters and is longer, and whether any has exactly those letters.
+func (mgr *GameManager) typeableTiles(letters string) (longer, exact bool) {
+	check := func(t *Tile) {
+		if t.Value == letters {
+			exact = true
+		} else if strings.HasPrefix(t.Value, letters) {
+			longer = true
+		}
+	}
+	if t := mgr.board.Get(mgr.highlight.idx); t != nil {
+		check(t)
+	}
+	for _, t := range mgr.tiles {
+		if t.Zone == ZoneTray {
+			check(t)
+		}
+	}
+	return longer, exact
+}
+
+// flushTyped places any letters held back by typeLetter.
+func (mgr *GameManager) flushTyped() {
+	if typed := mgr.highlight.typed; typed != "" {
+		mgr.highlight.typed = ""
+		mgr.findForHighlight(typed)
+	}
+}
+
 func (mgr *GameManager) toggleWordDir() {
 	mgr.highlight.dir = Vec{mgr.highlight.dir.Y, mgr.highlight.dir.X}
 }
@@ -49,6 +100,7 @@ func (mgr *GameManager) backspaceHighlight() {
 func (mgr *GameManager) highlightCoords(l Vec) {
 	mgr.highlight.active = true
 	mgr.highlight.idx = l
+	mgr.highlight.typed = ""
 }
 
 // highlightCanvas highlights the square at location l, which is definitely on the board.
@@ -60,6 +112,7 @@ func (mgr *GameManager) highlightCanvas(l Vec) {
 // unhighlight removes all active highlights.
 func (mgr *GameManager) unhighlight() {
 	mgr.highlight.active = false
+	mgr.highlight.typed = ""
 	mgr.highlight.idx = Vec{-1, -1}
 	mgr.highlight.dir = Vec{1, 0}
 }
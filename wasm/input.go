@@ -2,8 +2,12 @@ package main
 
 import (
 	"fmt"
+	"strings"
 	"syscall/js"
 	"unicode"
+	"unicode/utf8"
+
+	"github.com/kathrelkeld/speed-scrabble/msg"
 )
 
 type Move struct {
@@ -121,11 +125,43 @@ func (mgr *GameManager) onMouseUp(event js.Value) {
 	mgr.listens.EndMove()
 }
 
+// onHover shows the reason for an invalid or unused tile as a tooltip.
+func (mgr *GameManager) onHover(event js.Value) {
+	if mgr.move.active {
+		return
+	}
+	title := ""
+	if t := mgr.onTile(clickOffset(event)); t != nil && t.State != TileStateValid {
+		title = t.Reason
+	}
+	mgr.canvas.Set("title", title)
+}
+
+// onDoubleClick asks for the definitions of the words running through a tile on the board.
+func (mgr *GameManager) onDoubleClick(event js.Value) {
+	t := mgr.onTile(clickOffset(event))
+	if t == nil || t.Zone != ZoneBoard {
+		return
+	}
+	for _, w := range mgr.board.wordsThrough(t.Idx) {
+		m, _ := msg.NewSocketData(msg.Define, w)
+		mgr.websocketSend(m)
+	}
+}
+
 func (mgr *GameManager) onKeyDown(event js.Value) {
 	if !mgr.highlight.active {
 		return
 	}
 	k := event.Get("key").String()
+	if r, n := utf8.DecodeRuneInString(k); n == len(k) && unicode.IsLetter(r) {
+		mgr.typeLetter(strings.ToUpper(k))
+		mgr.unmarkAllTiles()
+		return
+	}
+	if k != "Shift" {
+		mgr.flushTyped()
+	}
 	switch k {
 	case "ArrowUp":
 		if event.Get("ctrlKey").Bool() {
@@ -160,10 +196,5 @@ func (mgr *GameManager) onKeyDown(event js.Value) {
 		mgr.backspaceHighlight()
 	case "Delete":
 		mgr.backspaceHighlight()
-	default:
-		if len(k) == 1 && unicode.IsLetter(rune(k[0])) {
-			mgr.findForHighlight(string(unicode.ToUpper(rune(k[0]))))
-			mgr.unmarkAllTiles()
-		}
 	}
 }
@@ -21,6 +21,8 @@ func NewListeners(mgr *GameManager) Listeners {
 		"mousemove": jsEventFuncOf(mgr.onMouseMove),
 		"mousedown": jsEventFuncOf(mgr.onMouseDown),
 		"keydown":   jsEventFuncOf(mgr.onKeyDown),
+		"hover":     jsEventFuncOf(mgr.onHover),
+		"dblclick":  jsEventFuncOf(mgr.onDoubleClick),
 	}
 }
 
@@ -35,6 +37,9 @@ func (l Listeners) removeListener(where js.Value, which string) {
 func (l Listeners) NewGame() {
 	canvas := js.Global().Get("document").Call("getElementById", "canvas")
 	l.addListener(canvas, "mousedown")
+	// Hovering stays active after the game ends so that the final score can be inspected.
+	canvas.Call("addEventListener", "mousemove", l["hover"])
+	l.addListener(canvas, "dblclick")
 	doc := js.Global().Get("document")
 	l.addListener(doc, "keydown")
 }
@@ -53,8 +58,9 @@ func (l Listeners) EndMove() {
 func (l *Listeners) EndGame() {
 	canvas := js.Global().Get("document").Call("getElementById", "canvas")
 	l.removeListener(canvas, "mousedown")
+	// A round may end during a move.
 	l.removeListener(canvas, "mousemove")
-	l.removeListener(canvas, "mousemove")
+	l.removeListener(canvas, "mouseup")
 	doc := js.Global().Get("document")
 	l.removeListener(doc, "keydown")
 }
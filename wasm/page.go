@@ -1,7 +1,12 @@
 package main
 
 import (
+	"fmt"
+	"sort"
+	"strings"
 	"syscall/js"
+
+	"github.com/kathrelkeld/speed-scrabble/msg"
 )
 
 func disableButton(id string) {
@@ -17,6 +22,8 @@ func DisableGameButtons() {
 	disableButton("addTile")
 	disableButton("verify")
 	disableButton("shuffleTiles")
+	disableButton("hint")
+	disableButton("repair")
 }
 
 func EnableGameButtons() {
@@ -24,6 +31,8 @@ func EnableGameButtons() {
 	enableButton("addTile")
 	enableButton("verify")
 	enableButton("shuffleTiles")
+	enableButton("hint")
+	enableButton("repair")
 }
 
 func newButton(name, id string, onclick js.Func) js.Value {
@@ -56,12 +65,43 @@ func (mgr *GameManager) setUpPage() {
 		})))
 	body.Call("appendChild", newButton("Verify", "verify", jsFuncOf(mgr.verify, mgr)))
 	body.Call("appendChild", newButton("Shuffle Tiles", "shuffleTiles", jsFuncOf(mgr.shuffleTiles, mgr)))
+	body.Call("appendChild", newButton("Hint", "hint", jsFuncOf(mgr.requestHint, mgr)))
+	body.Call("appendChild", newButton("Fix Board", "repair", jsFuncOf(mgr.requestRepair, mgr)))
 	DisableGameButtons()
 
+	// Add controls for computer players
+	levels := js.Global().Get("document").Call("createElement", "select")
+	levels.Set("id", "botLevel")
+	for _, l := range []string{"easy", "medium", "expert"} {
+		option := js.Global().Get("document").Call("createElement", "option")
+		option.Set("value", l)
+		option.Set("textContent", strings.Title(l))
+		levels.Call("appendChild", option)
+	}
+	levels.Set("value", "medium")
+	body.Call("appendChild", levels)
+	body.Call("appendChild", newButton("Add Bot", "addBot", jsFuncOf(mgr.addBot, mgr)))
+	body.Call("appendChild", newButton("Remove Bot", "removeBot", jsFuncOf(mgr.removeBot, mgr)))
+
 	messages := js.Global().Get("document").Call("createElement", "textbox")
 	messages.Set("id", "messages")
 	body.Call("appendChild", messages)
 
+	disputes := js.Global().Get("document").Call("createElement", "div")
+	disputes.Set("id", "disputes")
+	body.Call("appendChild", disputes)
+
+	body.Call("appendChild", newButton("Show Analysis", "toggleAnalysis", js.FuncOf(
+		func(this js.Value, args []js.Value) interface{} {
+			toggleAnalysis()
+			return nil
+		})))
+	disableButton("toggleAnalysis")
+	analysis := js.Global().Get("document").Call("createElement", "div")
+	analysis.Set("id", "analysis")
+	analysis.Get("style").Set("display", "none")
+	body.Call("appendChild", analysis)
+
 	canvas := js.Global().Get("document").Call("createElement", "canvas")
 	// TODO tie canvas size to default game size
 	canvas.Set("id", "canvas")
@@ -73,3 +113,242 @@ func (mgr *GameManager) setUpPage() {
 	mgr.canvas = Canvas(canvas)
 	mgr.listens = NewListeners(mgr)
 }
+
+// clearMessages empties the messages area.
+func clearMessages() {
+	js.Global().Get("document").Call("getElementById", "messages").Set("innerHTML", "")
+}
+
+// pageParam returns the value of the given query parameter of the page, if any, such as
+// "words" for the word list to play with or "lang" for the language.
+func pageParam(name string) string {
+	params := js.Global().Get("URLSearchParams").New(js.Global().Get("location").Get("search"))
+	if v := params.Call("get", name); v.Type() == js.TypeString {
+		return v.String()
+	}
+	return ""
+}
+
+// appendText adds a new element with the given tag and text to parent.
+func appendText(parent js.Value, tag, text string) {
+	e := js.Global().Get("document").Call("createElement", tag)
+	e.Set("textContent", text)
+	parent.Call("appendChild", e)
+}
+
+// showError shows a message from the server about something which went wrong.
+func showError(text string) {
+	messages := js.Global().Get("document").Call("getElementById", "messages")
+	appendText(messages, "p", text)
+}
+
+// showResults lists the final scores and awards for a round in the messages area.
+func showResults(r msg.ResultData) {
+	doc := js.Global().Get("document")
+	messages := doc.Call("getElementById", "messages")
+	clearMessages()
+
+	appendText(messages, "h3", fmt.Sprintf("Results (%v)", r.Rule))
+	players := doc.Call("createElement", "ol")
+	for _, p := range r.Players {
+		line := fmt.Sprintf("%v: %v pts", p.Name, p.Pts)
+		if p.Win {
+			line += " (finished)"
+		}
+		if p.Hints == 1 {
+			line += " (1 hint)"
+		} else if p.Hints > 1 {
+			line += fmt.Sprintf(" (%v hints)", p.Hints)
+		}
+		if p.Repairs > 0 {
+			line += " (repaired)"
+		}
+		appendText(players, "li", line)
+	}
+	messages.Call("appendChild", players)
+
+	if len(r.Awards) > 0 {
+		appendText(messages, "h3", "Awards")
+		awards := doc.Call("createElement", "ul")
+		for _, a := range r.Awards {
+			appendText(awards, "li", fmt.Sprintf("%v: %v - %v", a.Name, strings.Join(a.Players, ", "), a.Detail))
+		}
+		messages.Call("appendChild", awards)
+	}
+
+	if len(r.Definitions) > 0 {
+		appendText(messages, "h3", "Words played")
+		words := make([]string, 0, len(r.Definitions))
+		for w := range r.Definitions {
+			words = append(words, w)
+		}
+		sort.Strings(words)
+		defs := doc.Call("createElement", "ul")
+		for _, w := range words {
+			appendText(defs, "li", fmt.Sprintf("%v: %v", w, r.Definitions[w]))
+		}
+		messages.Call("appendChild", defs)
+	}
+}
+
+// showDefinition adds the definition of a word, asked for by double clicking it, to the
+// messages area.
+func showDefinition(d msg.DefinitionData) {
+	messages := js.Global().Get("document").Call("getElementById", "messages")
+	if !d.Found {
+		appendText(messages, "p", fmt.Sprintf("%v: no definition", d.Word))
+		return
+	}
+	appendText(messages, "p", fmt.Sprintf("%v: %v", d.Word, d.Definition))
+}
+
+// notInDictionary is the reason the server gives for words which may be challenged.
+const notInDictionary = "not in the dictionary"
+
+// showDisputes offers a button to challenge each word of the last score which is only
+// invalid because it is not in the dictionary.
+func (mgr *GameManager) showDisputes(nonwords []Word) {
+	disputes := js.Global().Get("document").Call("getElementById", "disputes")
+	disputes.Set("innerHTML", "")
+	for _, w := range nonwords {
+		if w.Reason != notInDictionary {
+			continue
+		}
+		word := w.Value
+		disputes.Call("appendChild", newButton("Challenge "+word, "challenge"+word, js.FuncOf(
+			func(this js.Value, args []js.Value) interface{} {
+				m, _ := msg.NewSocketData(msg.Challenge, word)
+				mgr.websocketSend(m)
+				return nil
+			})))
+	}
+}
+
+// showVote asks the player to accept or reject a word challenged by another player.
+func (mgr *GameManager) showVote(v msg.VoteData) {
+	doc := js.Global().Get("document")
+	prompt := doc.Call("createElement", "p")
+	prompt.Set("textContent", fmt.Sprintf("%v challenges %v. Should it count? ", v.Challenger, v.Word))
+	var accept, reject js.Func
+	answer := func(yes bool) {
+		m, _ := msg.NewSocketData(msg.Vote, msg.BallotData{Word: v.Word, Accept: yes})
+		mgr.websocketSend(m)
+		prompt.Call("remove")
+		accept.Release()
+		reject.Release()
+	}
+	accept = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		answer(true)
+		return nil
+	})
+	reject = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		answer(false)
+		return nil
+	})
+	prompt.Call("appendChild", newButton("Accept", "accept"+v.Word, accept))
+	prompt.Call("appendChild", newButton("Reject", "reject"+v.Word, reject))
+	doc.Call("getElementById", "disputes").Call("appendChild", prompt)
+}
+
+// showVoteResult tells the player how a vote on a challenged word ended.
+func showVoteResult(r msg.VoteResultData) {
+	outcome := "rejected"
+	if r.Accepted {
+		outcome = "accepted"
+	}
+	disputes := js.Global().Get("document").Call("getElementById", "disputes")
+	appendText(disputes, "p", fmt.Sprintf("%v was %v, %v to %v.", r.Word, outcome, r.For, r.Against))
+}
+
+// showAnalysis fills in the analysis area with what the player could have made this round,
+// next to the board they made, and lets them show it.
+func (mgr *GameManager) showAnalysis(a msg.AnalysisData) {
+	doc := js.Global().Get("document")
+	analysis := doc.Call("getElementById", "analysis")
+	analysis.Set("innerHTML", "")
+
+	appendText(analysis, "h3", "What you could have made")
+	boards := doc.Call("createElement", "table")
+	titles := doc.Call("createElement", "tr")
+	appendText(titles, "th", "Your board")
+	appendText(titles, "th", "Using every tile")
+	boards.Call("appendChild", titles)
+	grids := doc.Call("createElement", "tr")
+	for _, placed := range [][]msg.PlacedTile{mgr.board.placedTiles(), a.Board} {
+		cell := doc.Call("createElement", "td")
+		if len(placed) == 0 {
+			appendText(cell, "p", "No board")
+		} else {
+			appendText(cell, "pre", gridText(placed))
+		}
+		grids.Call("appendChild", cell)
+	}
+	boards.Call("appendChild", grids)
+	analysis.Call("appendChild", boards)
+
+	if a.Longest != "" {
+		appendText(analysis, "p", "Longest word: "+a.Longest)
+	}
+	if len(a.Missed) > 0 {
+		appendText(analysis, "p", "Best words you did not play:")
+		missed := doc.Call("createElement", "ol")
+		for _, w := range a.Missed {
+			appendText(missed, "li", fmt.Sprintf("%v: %v pts", w.Word, w.Pts))
+		}
+		analysis.Call("appendChild", missed)
+	}
+	enableButton("toggleAnalysis")
+}
+
+// clearAnalysis empties and hides the analysis area.
+func clearAnalysis() {
+	doc := js.Global().Get("document")
+	analysis := doc.Call("getElementById", "analysis")
+	analysis.Set("innerHTML", "")
+	analysis.Get("style").Set("display", "none")
+	doc.Call("getElementById", "toggleAnalysis").Set("innerHTML", "Show Analysis")
+	disableButton("toggleAnalysis")
+}
+
+// toggleAnalysis shows the analysis area if it is hidden, and hides it otherwise.
+func toggleAnalysis() {
+	doc := js.Global().Get("document")
+	style := doc.Call("getElementById", "analysis").Get("style")
+	button := doc.Call("getElementById", "toggleAnalysis")
+	if style.Get("display").String() == "none" {
+		style.Set("display", "block")
+		button.Set("innerHTML", "Hide Analysis")
+	} else {
+		style.Set("display", "none")
+		button.Set("innerHTML", "Show Analysis")
+	}
+}
+
+// gridText lays out placed tiles as rows of text, trimmed to the tiles, with a dot for each
+// empty square.
+func gridText(placed []msg.PlacedTile) string {
+	lo, hi := Vec{placed[0].X, placed[0].Y}, Vec{placed[0].X, placed[0].Y}
+	for _, p := range placed {
+		lo = Vec{min(lo.X, p.X), min(lo.Y, p.Y)}
+		hi = Vec{max(hi.X, p.X), max(hi.Y, p.Y)}
+	}
+	cells := make([][]string, hi.Y-lo.Y+1)
+	for j := range cells {
+		cells[j] = make([]string, hi.X-lo.X+1)
+		for i := range cells[j] {
+			cells[j][i] = "."
+		}
+	}
+	for _, p := range placed {
+		cells[p.Y-lo.Y][p.X-lo.X] = p.Value
+	}
+	var rows []string
+	for _, row := range cells {
+		line := ""
+		for _, c := range row {
+			line += fmt.Sprintf("%-3v", c)
+		}
+		rows = append(rows, strings.TrimRight(line, " "))
+	}
+	return strings.Join(rows, "\n")
+}
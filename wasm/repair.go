@@ -0,0 +1,71 @@
+package main
+
+import (
+	"time"
+
+	"github.com/kathrelkeld/speed-scrabble/msg"
+)
+
+// Each tile of a repair glides to its new place over repairFrames frames.
+const (
+	repairFrames    = 12
+	repairFrameTime = 25 * time.Millisecond
+)
+
+// requestRepair asks for the moves which make the board as it is now valid and connected.
+func (mgr *GameManager) requestRepair() {
+	mgr.hint = nil
+	mgr.sendBoard(msg.Repair)
+}
+
+// tileByID returns the given tile, or nil if it was never served.
+func (mgr *GameManager) tileByID(id int) *Tile {
+	for _, t := range mgr.tiles {
+		if t.ID == id {
+			return t
+		}
+	}
+	return nil
+}
+
+// animateRepair glides each tile of a repair to its new place in turn.  Tiles which the
+// player has moved since asking are left alone, and the animation stops if the round ends.
+func (mgr *GameManager) animateRepair(moves []msg.RepairMove) {
+	DisableGameButtons()
+	go func() {
+		defer func() {
+			if mgr.state == StatePlaying {
+				EnableGameButtons()
+			}
+			mgr.unmarkAllTiles()
+			mgr.draw()
+		}()
+		for _, m := range moves {
+			from := Vec{m.From.X, m.From.Y}
+			t := mgr.tileByID(m.From.ID)
+			if t == nil || t.Zone != ZoneBoard || t.Idx != from {
+				continue
+			}
+			end := mgr.tray.Loc
+			if m.To != nil {
+				end = mgr.board.canvasStart(Vec{m.To.X, m.To.Y})
+			}
+			t.pickUp()
+			t.Zone = ZoneMoving
+			start := t.Loc
+			for i := 1; i <= repairFrames; i++ {
+				if mgr.state != StatePlaying {
+					return
+				}
+				t.Loc = Add(start, ScaleDown(ScaleUp(Sub(end, start), i), repairFrames))
+				mgr.draw()
+				time.Sleep(repairFrameTime)
+			}
+			if m.To != nil {
+				t.addToBoard(Vec{m.To.X, m.To.Y})
+			} else {
+				t.sendToTray()
+			}
+		}
+	}()
+}
@@ -30,3 +30,17 @@ func Mult(a, b Vec) Vec {
 func (loc Vec) inTarget(start, end Vec) bool {
 	return loc.X > start.X && loc.X < end.X && loc.Y > start.Y && loc.Y < end.Y
 }
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}